@@ -93,6 +93,26 @@ func BenchmarkMapMixedLoad_SynchronizedMap(b *testing.B) {
 	}
 }
 
+func BenchmarkMapMixedLoad_LockFreeMap(b *testing.B) {
+	m := async.NewLockFreeMap[mkey, int]()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		benchmarkMixedConcurrentLoad(m)
+	}
+}
+
+func BenchmarkMapMixedLoad_LockFreeMapWithHash(b *testing.B) {
+	m := async.NewLockFreeMapWithHash[mkey, int](
+		func(k mkey) uint64 { return k.i },
+	)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		benchmarkMixedConcurrentLoad(m)
+	}
+}
+
 func benchmarkReadConcurrentLoad(m async.Map[mkey, int]) {
 	var wg sync.WaitGroup
 	for r := 0; r < iter; r++ {
@@ -185,6 +205,28 @@ func BenchmarkMapReadLoad_SynchronizedMap(b *testing.B) {
 	}
 }
 
+func BenchmarkMapReadLoad_LockFreeMap(b *testing.B) {
+	m := async.NewLockFreeMap[mkey, int]()
+	fillMap(m)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		benchmarkReadConcurrentLoad(m)
+	}
+}
+
+func BenchmarkMapReadLoad_LockFreeMapWithHash(b *testing.B) {
+	m := async.NewLockFreeMapWithHash[mkey, int](
+		func(k mkey) uint64 { return k.i },
+	)
+	fillMap(m)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		benchmarkReadConcurrentLoad(m)
+	}
+}
+
 func benchmarkWriteConcurrentLoad(m async.Map[mkey, int]) {
 	var wg sync.WaitGroup
 	for r := 0; r < iter; r++ {
@@ -255,6 +297,77 @@ func BenchmarkMapWriteLoad_SynchronizedMap(b *testing.B) {
 	}
 }
 
+func BenchmarkMapWriteLoad_LockFreeMap(b *testing.B) {
+	m := async.NewLockFreeMap[mkey, int]()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		benchmarkWriteConcurrentLoad(m)
+	}
+}
+
+func BenchmarkMapWriteLoad_LockFreeMapWithHash(b *testing.B) {
+	m := async.NewLockFreeMapWithHash[mkey, int](
+		func(k mkey) uint64 { return k.i },
+	)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		benchmarkWriteConcurrentLoad(m)
+	}
+}
+
+// point is a small value type to compare the Map and ValueMap allocations.
+type point struct {
+	x, y int32
+}
+
+const storeLoadKeys = 1024
+
+func benchmarkMapStoreLoad(b *testing.B, m async.Map[int, point]) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		key := n % storeLoadKeys
+		m.Put(key, &point{int32(n), int32(n)})
+		_ = *m.Get(key)
+	}
+}
+
+func benchmarkValueMapStoreLoad(b *testing.B, m async.ValueMap[int, point]) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		key := n % storeLoadKeys
+		m.Store(key, point{int32(n), int32(n)})
+		_, _ = m.Load(key)
+	}
+}
+
+func BenchmarkMapStoreLoad_ConcurrentMap(b *testing.B) {
+	benchmarkMapStoreLoad(b, async.NewConcurrentMap[int, point]())
+}
+
+func BenchmarkMapStoreLoad_ConcurrentValueMap(b *testing.B) {
+	benchmarkValueMapStoreLoad(b, async.NewConcurrentValueMap[int, point]())
+}
+
+func BenchmarkMapStoreLoad_ShardedMap(b *testing.B) {
+	benchmarkMapStoreLoad(b, async.NewShardedMap[int, point](shards))
+}
+
+func BenchmarkMapStoreLoad_ShardedValueMap(b *testing.B) {
+	benchmarkValueMapStoreLoad(b, async.NewShardedValueMap[int, point](shards))
+}
+
+func BenchmarkMapStoreLoad_SynchronizedMap(b *testing.B) {
+	benchmarkMapStoreLoad(b, async.NewSynchronizedMap[int, point]())
+}
+
+func BenchmarkMapStoreLoad_SynchronizedValueMap(b *testing.B) {
+	benchmarkValueMapStoreLoad(b, async.NewSynchronizedValueMap[int, point]())
+}
+
 type mkey struct {
 	i uint64
 	s string
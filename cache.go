@@ -0,0 +1,530 @@
+package async
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EvictionPolicy specifies the order in which a [Cache] evicts entries
+// when its capacity is exceeded.
+type EvictionPolicy int
+
+const (
+	// EvictionPolicyLRU evicts the least recently used entry first.
+	EvictionPolicyLRU EvictionPolicy = iota
+	// EvictionPolicyLFU evicts the least frequently used entry first,
+	// breaking ties by evicting the least recently used one.
+	EvictionPolicyLFU
+)
+
+// EvictionCause represents the reason for which a [Cache] entry was evicted.
+type EvictionCause int
+
+const (
+	// EvictionCauseExpired means that the time-to-live of the entry elapsed.
+	EvictionCauseExpired EvictionCause = iota
+	// EvictionCauseCapacity means that the entry was evicted to keep the
+	// cache within its capacity bounds.
+	EvictionCauseCapacity
+)
+
+// CacheConfig represents the [Cache] configuration.
+type CacheConfig[K comparable, V any] struct {
+	// Shards is the number of partitions of the cache. Must be positive.
+	// It is reduced to MaxEntries or MaxWeight if either limit is set and
+	// smaller, so that each shard can hold at least one entry.
+	Shards int
+	// MaxEntries is the maximum number of entries in the cache, or zero
+	// for no limit. The limit is split evenly between the shards, so that
+	// the shard limits add up to it, and each shard evicts its entries
+	// independently once its own limit is reached.
+	MaxEntries int
+	// MaxWeight is the maximum total weight of the entries in the cache,
+	// or zero for no limit. It is split between the shards like MaxEntries.
+	MaxWeight int64
+	// Weigher calculates the weight of an entry. If nil, every entry
+	// has a weight of one.
+	Weigher func(K, *V) int64
+	// TTL is the default time-to-live of the entries, or zero for entries
+	// that do not expire.
+	TTL time.Duration
+	// Policy is the eviction policy applied in each shard.
+	Policy EvictionPolicy
+	// Loader is an optional function to load the values for missing keys.
+	Loader func(context.Context, K) (*V, error)
+	// OnEviction is an optional callback invoked when an entry is evicted.
+	// It is not invoked for explicitly removed or replaced entries.
+	OnEviction func(K, *V, EvictionCause)
+	// HashFunc is an optional hash function to calculate the shard number
+	// for a key. If nil, the default hash function is used.
+	HashFunc func(K) uint64
+}
+
+// CacheStats represents a point-in-time snapshot of [Cache] statistics.
+type CacheStats struct {
+	Hits       int64
+	Misses     int64
+	Loads      int64
+	LoadErrors int64
+	Evictions  int64
+}
+
+// HitRatio returns the ratio of cache hits to the total number of lookups,
+// or zero if there were no lookups.
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Cache is a concurrent, sharded in-memory cache with per-entry expiration,
+// entry count and weight based capacity bounds, and LRU or LFU eviction
+// applied independently in each shard. Misses can be filled using a loader
+// function, which is called exactly once per key for concurrent lookups.
+// A Cache must not be copied.
+type Cache[K comparable, V any] struct {
+	shards     []*cacheShard[K, V]
+	hashFunc   func(K) uint64
+	ttl        time.Duration
+	loader     func(context.Context, K) (*V, error)
+	onEviction func(K, *V, EvictionCause)
+	flight     *SingleFlight[K, *V]
+
+	hits       atomic.Int64
+	misses     atomic.Int64
+	loads      atomic.Int64
+	loadErrors atomic.Int64
+	evictions  atomic.Int64
+}
+
+// NewCache returns a new Cache with the given configuration.
+// If the number of shards is not positive, NewCache will panic.
+func NewCache[K comparable, V any](config *CacheConfig[K, V]) *Cache[K, V] {
+	if config.Shards < 1 {
+		panic(fmt.Sprintf("nonpositive shards: %d", config.Shards))
+	}
+	hashFunc := config.HashFunc
+	if hashFunc == nil {
+		hashFunc = newHashFunc[K]()
+	}
+	weigher := config.Weigher
+	if weigher == nil {
+		weigher = func(K, *V) int64 { return 1 }
+	}
+
+	n := config.Shards
+	if config.MaxEntries > 0 {
+		n = min(n, config.MaxEntries)
+	}
+	if config.MaxWeight > 0 {
+		n = int(min(int64(n), config.MaxWeight))
+	}
+	shards := make([]*cacheShard[K, V], n)
+	for i := range shards {
+		shards[i] = &cacheShard[K, V]{
+			entries:    make(map[K]*cacheEntry[K, V]),
+			policy:     newCachePolicy[K, V](config.Policy),
+			weigher:    weigher,
+			maxEntries: shardLimit(int64(config.MaxEntries), len(shards), i),
+			maxWeight:  shardLimit(config.MaxWeight, len(shards), i),
+		}
+	}
+	return &Cache[K, V]{
+		shards:     shards,
+		hashFunc:   hashFunc,
+		ttl:        config.TTL,
+		loader:     config.Loader,
+		onEviction: config.OnEviction,
+		flight:     NewSingleFlight[K, *V](),
+	}
+}
+
+// Get returns the value to which the specified key is mapped. On a miss,
+// the value is loaded using the configured loader and stored in the cache;
+// concurrent misses for the same key share a single loader call. If no
+// loader is configured, Get returns nil on a miss.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (*V, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+	if c.loader == nil {
+		return nil, nil
+	}
+	return c.flight.Do(ctx, key, func(ctx context.Context) (*V, error) {
+		// the value may have been stored since the lookup
+		value, ok, expired := c.shard(key).get(key, time.Now().UnixNano())
+		c.notify(expired)
+		if ok {
+			return value, nil
+		}
+		c.loads.Add(1)
+		value, err := c.loader(ctx, key)
+		if err != nil {
+			c.loadErrors.Add(1)
+			return nil, err
+		}
+		if value != nil {
+			c.Put(key, value)
+		}
+		return value, nil
+	}).Get(ctx)
+}
+
+// GetIfPresent returns the value to which the specified key is mapped, or
+// nil if the cache contains no unexpired entry for the key. An expired entry
+// found by the lookup is evicted.
+func (c *Cache[K, V]) GetIfPresent(key K) *V {
+	value, _ := c.lookup(key)
+	return value
+}
+
+// Put associates the specified value with the specified key in the cache,
+// using the default time-to-live.
+func (c *Cache[K, V]) Put(key K, value *V) {
+	c.PutWithTTL(key, value, c.ttl)
+}
+
+// PutWithTTL associates the specified value with the specified key in the
+// cache. The entry expires after the given time-to-live, unless it is zero.
+func (c *Cache[K, V]) PutWithTTL(key K, value *V, ttl time.Duration) {
+	var expiry int64
+	if ttl > 0 {
+		expiry = time.Now().Add(ttl).UnixNano()
+	}
+	c.notify(c.shard(key).put(key, value, expiry))
+}
+
+// Remove removes the entry for a key from the cache if it is present,
+// returning the previous value or nil if none.
+func (c *Cache[K, V]) Remove(key K) *V {
+	return c.shard(key).remove(key)
+}
+
+// Clear removes all of the entries from the cache.
+func (c *Cache[K, V]) Clear() {
+	for _, shard := range c.shards {
+		shard.clear()
+	}
+}
+
+// CleanUp removes all expired entries from the cache.
+func (c *Cache[K, V]) CleanUp() {
+	now := time.Now().UnixNano()
+	for _, shard := range c.shards {
+		c.notify(shard.removeExpired(now))
+	}
+}
+
+// Size returns the number of entries in the cache, which may include
+// expired entries that have not been looked up or cleaned up yet.
+func (c *Cache[K, V]) Size() int {
+	var size int
+	for _, shard := range c.shards {
+		size += shard.size()
+	}
+	return size
+}
+
+// Stats returns the cache statistics.
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Loads:      c.loads.Load(),
+		LoadErrors: c.loadErrors.Load(),
+		Evictions:  c.evictions.Load(),
+	}
+}
+
+// lookup returns the unexpired value for the key and records the result
+// in the statistics. An expired entry for the key is evicted.
+func (c *Cache[K, V]) lookup(key K) (*V, bool) {
+	value, ok, expired := c.shard(key).get(key, time.Now().UnixNano())
+	c.notify(expired)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// notify records the evicted entries and invokes the eviction callback.
+func (c *Cache[K, V]) notify(evicted []cacheEviction[K, V]) {
+	c.evictions.Add(int64(len(evicted)))
+	if c.onEviction == nil {
+		return
+	}
+	for _, eviction := range evicted {
+		c.onEviction(eviction.entry.key, eviction.entry.value, eviction.cause)
+	}
+}
+
+// shard returns the shard for the key.
+func (c *Cache[K, V]) shard(key K) *cacheShard[K, V] {
+	return c.shards[c.hashFunc(key)%uint64(len(c.shards))]
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key    K
+	value  *V
+	expiry int64 // in Unix nanoseconds, zero if the entry does not expire
+	weight int64
+
+	// eviction policy bookkeeping
+	element   *list.Element
+	index     int
+	frequency uint64
+	access    uint64
+}
+
+func (e *cacheEntry[K, V]) expired(now int64) bool {
+	return e.expiry != 0 && now >= e.expiry
+}
+
+type cacheEviction[K comparable, V any] struct {
+	entry *cacheEntry[K, V]
+	cause EvictionCause
+}
+
+// cacheShard is a single partition of the cache, guarded by a mutex.
+type cacheShard[K comparable, V any] struct {
+	mtx        sync.Mutex
+	entries    map[K]*cacheEntry[K, V]
+	policy     cachePolicy[K, V]
+	weigher    func(K, *V) int64
+	weight     int64
+	maxEntries int64 // math.MaxInt64 for no limit
+	maxWeight  int64 // math.MaxInt64 for no limit
+}
+
+// get returns the unexpired value for the key. If the entry for the key
+// is expired, it is removed and returned as evicted.
+func (s *cacheShard[K, V]) get(key K, now int64) (*V, bool, []cacheEviction[K, V]) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if entry.expired(now) {
+		s.delete(entry)
+		return nil, false, []cacheEviction[K, V]{{entry, EvictionCauseExpired}}
+	}
+	s.policy.access(entry)
+	return entry.value, true, nil
+}
+
+func (s *cacheShard[K, V]) put(key K, value *V, expiry int64) []cacheEviction[K, V] {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if entry, ok := s.entries[key]; ok {
+		s.delete(entry)
+	}
+	entry := &cacheEntry[K, V]{
+		key:    key,
+		value:  value,
+		expiry: expiry,
+		weight: s.weigher(key, value),
+	}
+	if entry.weight > s.maxWeight {
+		// the entry can never fit, so it is evicted right away
+		return []cacheEviction[K, V]{{entry, EvictionCauseCapacity}}
+	}
+	// make room before adding the entry, so that it is never chosen as a victim
+	evicted := s.evict(1, entry.weight)
+	s.entries[key] = entry
+	s.weight += entry.weight
+	s.policy.add(entry)
+	return evicted
+}
+
+func (s *cacheShard[K, V]) remove(key K) *V {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	s.delete(entry)
+	return entry.value
+}
+
+func (s *cacheShard[K, V]) removeExpired(now int64) []cacheEviction[K, V] {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var evicted []cacheEviction[K, V]
+	for _, entry := range s.entries {
+		if entry.expired(now) {
+			s.delete(entry)
+			evicted = append(evicted, cacheEviction[K, V]{entry, EvictionCauseExpired})
+		}
+	}
+	return evicted
+}
+
+func (s *cacheShard[K, V]) clear() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, entry := range s.entries {
+		s.policy.remove(entry)
+	}
+	s.entries = make(map[K]*cacheEntry[K, V])
+	s.weight = 0
+}
+
+func (s *cacheShard[K, V]) size() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.entries)
+}
+
+// evict removes entries chosen by the eviction policy until the given number
+// of entries and weight can be added within the shard capacity bounds.
+// Expired victims are reported as such.
+func (s *cacheShard[K, V]) evict(entries, weight int64) []cacheEviction[K, V] {
+	var evicted []cacheEviction[K, V]
+	now := time.Now().UnixNano()
+	for len(s.entries) > 0 &&
+		(int64(len(s.entries))+entries > s.maxEntries || s.weight+weight > s.maxWeight) {
+		entry := s.policy.victim()
+		s.delete(entry)
+		cause := EvictionCauseCapacity
+		if entry.expired(now) {
+			cause = EvictionCauseExpired
+		}
+		evicted = append(evicted, cacheEviction[K, V]{entry, cause})
+	}
+	return evicted
+}
+
+func (s *cacheShard[K, V]) delete(entry *cacheEntry[K, V]) {
+	delete(s.entries, entry.key)
+	s.weight -= entry.weight
+	s.policy.remove(entry)
+}
+
+// cachePolicy orders the entries of a cache shard for eviction.
+type cachePolicy[K comparable, V any] interface {
+	add(*cacheEntry[K, V])
+	access(*cacheEntry[K, V])
+	remove(*cacheEntry[K, V])
+	victim() *cacheEntry[K, V]
+}
+
+func newCachePolicy[K comparable, V any](policy EvictionPolicy) cachePolicy[K, V] {
+	switch policy {
+	case EvictionPolicyLRU:
+		return &lruPolicy[K, V]{list: list.New()}
+	case EvictionPolicyLFU:
+		return &lfuPolicy[K, V]{}
+	default:
+		panic(fmt.Sprintf("unknown eviction policy: %d", policy))
+	}
+}
+
+// lruPolicy keeps the entries in a list ordered by recency of use.
+type lruPolicy[K comparable, V any] struct {
+	list *list.List
+}
+
+func (p *lruPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	entry.element = p.list.PushFront(entry)
+}
+
+func (p *lruPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	p.list.MoveToFront(entry.element)
+}
+
+func (p *lruPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	p.list.Remove(entry.element)
+}
+
+func (p *lruPolicy[K, V]) victim() *cacheEntry[K, V] {
+	return p.list.Back().Value.(*cacheEntry[K, V])
+}
+
+// lfuPolicy keeps the entries in a min-heap ordered by access frequency,
+// and then by recency of use.
+type lfuPolicy[K comparable, V any] struct {
+	entries []*cacheEntry[K, V]
+	clock   uint64
+}
+
+var _ heap.Interface = (*lfuPolicy[int, any])(nil)
+
+func (p *lfuPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	p.clock++
+	entry.frequency = 1
+	entry.access = p.clock
+	heap.Push(p, entry)
+}
+
+func (p *lfuPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	p.clock++
+	entry.frequency++
+	entry.access = p.clock
+	heap.Fix(p, entry.index)
+}
+
+func (p *lfuPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	heap.Remove(p, entry.index)
+}
+
+func (p *lfuPolicy[K, V]) victim() *cacheEntry[K, V] {
+	return p.entries[0]
+}
+
+func (p *lfuPolicy[K, V]) Len() int {
+	return len(p.entries)
+}
+
+func (p *lfuPolicy[K, V]) Less(i, j int) bool {
+	if p.entries[i].frequency != p.entries[j].frequency {
+		return p.entries[i].frequency < p.entries[j].frequency
+	}
+	return p.entries[i].access < p.entries[j].access
+}
+
+func (p *lfuPolicy[K, V]) Swap(i, j int) {
+	p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	p.entries[i].index = i
+	p.entries[j].index = j
+}
+
+func (p *lfuPolicy[K, V]) Push(x any) {
+	entry := x.(*cacheEntry[K, V])
+	entry.index = len(p.entries)
+	p.entries = append(p.entries, entry)
+}
+
+func (p *lfuPolicy[K, V]) Pop() any {
+	n := len(p.entries) - 1
+	entry := p.entries[n]
+	p.entries[n] = nil
+	p.entries = p.entries[:n]
+	return entry
+}
+
+// shardLimit returns the share of the cache limit for the i-th of n shards.
+// The remainder of the division is distributed among the first shards, so
+// that the shard limits add up to the cache limit. A zero limit means that
+// the cache is not limited.
+func shardLimit(limit int64, n, i int) int64 {
+	if limit == 0 {
+		return math.MaxInt64
+	}
+	share := limit / int64(n)
+	if int64(i) < limit%int64(n) {
+		share++
+	}
+	return share
+}
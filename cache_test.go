@@ -0,0 +1,279 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+	"github.com/reugn/async/internal/ptr"
+)
+
+func TestCache_PutGet(t *testing.T) {
+	cache := NewCache(&CacheConfig[string, int]{Shards: 4})
+	cache.Put("a", ptr.Of(1))
+	cache.Put("b", ptr.Of(2))
+
+	value, err := cache.Get(context.Background(), "a")
+	assert.IsNil(t, err)
+	assert.Equal(t, 1, *value)
+	assert.Equal(t, 2, *cache.GetIfPresent("b"))
+	assert.IsNil(t, cache.GetIfPresent("c"))
+	value, err = cache.Get(context.Background(), "c")
+	assert.IsNil(t, err)
+	assert.IsNil(t, value)
+	assert.Equal(t, 2, cache.Size())
+
+	assert.Equal(t, 1, *cache.Remove("a"))
+	assert.IsNil(t, cache.Remove("a"))
+	assert.Equal(t, 1, cache.Size())
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Size())
+	assert.IsNil(t, cache.GetIfPresent("b"))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+	assert.Equal(t, 0.4, stats.HitRatio())
+}
+
+func TestCache_TTL(t *testing.T) {
+	var mtx sync.Mutex
+	evicted := make(map[string]EvictionCause)
+	cache := NewCache(&CacheConfig[string, int]{
+		Shards: 1,
+		TTL:    20 * time.Millisecond,
+		OnEviction: func(key string, _ *int, cause EvictionCause) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			evicted[key] = cause
+		},
+	})
+	cache.Put("a", ptr.Of(1))
+	cache.PutWithTTL("b", ptr.Of(2), time.Hour)
+	cache.PutWithTTL("c", ptr.Of(3), 0)
+	assert.Equal(t, 1, *cache.GetIfPresent("a"))
+
+	cache.PutWithTTL("d", ptr.Of(4), 20*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 4, cache.Size())
+	// an expired entry is evicted when it is looked up
+	assert.IsNil(t, cache.GetIfPresent("a"))
+	assert.Equal(t, 2, *cache.GetIfPresent("b"))
+	assert.Equal(t, 3, *cache.GetIfPresent("c"))
+	assert.Equal(t, 3, cache.Size())
+	assert.Equal(t, map[string]EvictionCause{"a": EvictionCauseExpired}, evicted)
+
+	cache.CleanUp()
+	assert.Equal(t, 2, cache.Size())
+	assert.Equal(t, map[string]EvictionCause{"a": EvictionCauseExpired, "d": EvictionCauseExpired}, evicted)
+	assert.Equal(t, int64(2), cache.Stats().Evictions)
+}
+
+func TestCache_LRU(t *testing.T) {
+	var evicted []string
+	cache := NewCache(&CacheConfig[string, int]{
+		Shards:     1,
+		MaxEntries: 2,
+		Policy:     EvictionPolicyLRU,
+		OnEviction: func(key string, _ *int, cause EvictionCause) {
+			assert.Equal(t, EvictionCauseCapacity, cause)
+			evicted = append(evicted, key)
+		},
+	})
+	cache.Put("a", ptr.Of(1))
+	cache.Put("b", ptr.Of(2))
+	cache.GetIfPresent("a")
+	cache.Put("c", ptr.Of(3))
+
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, 1, *cache.GetIfPresent("a"))
+	assert.Equal(t, 3, *cache.GetIfPresent("c"))
+
+	// replacing an entry does not evict
+	cache.Put("a", ptr.Of(4))
+	assert.Equal(t, []string{"b"}, evicted)
+	cache.Put("d", ptr.Of(5))
+	assert.Equal(t, []string{"b", "c"}, evicted)
+	assert.Equal(t, 2, cache.Size())
+	assert.Equal(t, int64(2), cache.Stats().Evictions)
+}
+
+func TestCache_LFU(t *testing.T) {
+	var evicted []string
+	cache := NewCache(&CacheConfig[string, int]{
+		Shards:     1,
+		MaxEntries: 3,
+		Policy:     EvictionPolicyLFU,
+		OnEviction: func(key string, _ *int, _ EvictionCause) {
+			evicted = append(evicted, key)
+		},
+	})
+	cache.Put("a", ptr.Of(1))
+	cache.Put("b", ptr.Of(2))
+	cache.Put("c", ptr.Of(3))
+	for range 3 {
+		cache.GetIfPresent("a")
+	}
+	cache.GetIfPresent("b")
+	cache.GetIfPresent("c")
+	cache.GetIfPresent("b")
+
+	cache.Put("d", ptr.Of(4))
+	assert.Equal(t, []string{"c"}, evicted)
+	cache.Put("e", ptr.Of(5))
+	assert.Equal(t, []string{"c", "d"}, evicted)
+	cache.Remove("b")
+	cache.Put("f", ptr.Of(6))
+	cache.Put("g", ptr.Of(7))
+	assert.Equal(t, []string{"c", "d", "e"}, evicted)
+	assert.Equal(t, 1, *cache.GetIfPresent("a"))
+}
+
+func TestCache_Weight(t *testing.T) {
+	var evicted []string
+	cache := NewCache(&CacheConfig[string, string]{
+		Shards:    1,
+		MaxWeight: 10,
+		Weigher: func(_ string, value *string) int64 {
+			return int64(len(*value))
+		},
+		OnEviction: func(key string, _ *string, _ EvictionCause) {
+			evicted = append(evicted, key)
+		},
+	})
+	cache.Put("a", ptr.Of("abcd"))
+	cache.Put("b", ptr.Of("abcd"))
+	assert.IsNil(t, evicted)
+	cache.Put("c", ptr.Of("abcdef"))
+	assert.Equal(t, []string{"a"}, evicted)
+
+	// an entry heavier than the capacity is evicted right away
+	cache.Put("d", ptr.Of("abcdefghijk"))
+	assert.Equal(t, []string{"a", "d"}, evicted)
+	assert.Equal(t, "abcd", *cache.GetIfPresent("b"))
+	assert.Equal(t, "abcdef", *cache.GetIfPresent("c"))
+	assert.Equal(t, 2, cache.Size())
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	for _, maxEntries := range []int{1, 3, 10, 13} {
+		var evicted atomic.Int32
+		cache := NewCache(&CacheConfig[int, int]{
+			Shards:     4,
+			MaxEntries: maxEntries,
+			OnEviction: func(_ int, _ *int, _ EvictionCause) {
+				evicted.Add(1)
+			},
+		})
+		for i := 0; i < 100; i++ {
+			cache.Put(i, ptr.Of(i))
+			if cache.Size() > maxEntries {
+				t.Fatalf("size %d exceeds the limit of %d", cache.Size(), maxEntries)
+			}
+		}
+		assert.Equal(t, 100, cache.Size()+int(evicted.Load()))
+	}
+}
+
+func TestCache_FewerEntriesThanShards(t *testing.T) {
+	var loads atomic.Int32
+	cache := NewCache(&CacheConfig[int, int]{
+		Shards:     16,
+		MaxEntries: 4,
+		Loader: func(_ context.Context, key int) (*int, error) {
+			loads.Add(1)
+			return ptr.Of(key), nil
+		},
+	})
+	// each key can be cached
+	for key := 0; key < 100; key++ {
+		for range 2 {
+			value, err := cache.Get(context.Background(), key)
+			assert.IsNil(t, err)
+			assert.Equal(t, key, *value)
+		}
+		assert.Equal(t, int32(key+1), loads.Load())
+		if cache.Size() > 4 {
+			t.Fatalf("size %d exceeds the limit", cache.Size())
+		}
+	}
+	assert.Equal(t, 4, len(cache.shards))
+
+	weighted := NewCache(&CacheConfig[int, int]{Shards: 16, MaxWeight: 2})
+	weighted.Put(1, ptr.Of(1))
+	assert.Equal(t, 1, *weighted.GetIfPresent(1))
+	assert.Equal(t, 2, len(weighted.shards))
+}
+
+func TestCache_Loader(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	cache := NewCache(&CacheConfig[int, int]{
+		Shards: 4,
+		Loader: func(_ context.Context, key int) (*int, error) {
+			calls.Add(1)
+			<-release
+			if key < 0 {
+				return nil, errors.New("negative key")
+			}
+			return ptr.Of(key * 2), nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	results := make([]*int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = cache.Get(context.Background(), 21)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, result := range results {
+		assert.Equal(t, 42, *result)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 42, *cache.GetIfPresent(21))
+
+	_, err := cache.Get(context.Background(), -1)
+	assert.ErrorContains(t, err, "negative key")
+	assert.IsNil(t, cache.GetIfPresent(-1))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.Loads)
+	assert.Equal(t, int64(1), stats.LoadErrors)
+}
+
+func TestCache_LoaderCanceled(t *testing.T) {
+	cache := NewCache(&CacheConfig[int, int]{
+		Shards: 1,
+		Loader: func(ctx context.Context, _ int) (*int, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := cache.Get(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCache_Panics(t *testing.T) {
+	assert.PanicMsgContains(t, func() {
+		NewCache(&CacheConfig[int, int]{})
+	}, "nonpositive shards")
+	assert.PanicMsgContains(t, func() {
+		NewCache(&CacheConfig[int, int]{Shards: 1, Policy: EvictionPolicy(5)})
+	}, "unknown eviction policy")
+}
@@ -0,0 +1,191 @@
+package async
+
+import (
+	"cmp"
+	"container/heap"
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync/atomic"
+)
+
+// maxCounterStripes is the maximum number of cells of a counter.
+const maxCounterStripes = 64
+
+// CounterEntry represents a key and its count in a CounterMap.
+type CounterEntry[K comparable] struct {
+	Key   K
+	Count int64
+}
+
+// CounterMap is a thread-safe map of int64 counters. The keys are partitioned
+// using an async.ShardedMap, and each counter is striped: it is updated using
+// a single atomic cell until contention is detected, after which the updates
+// are spread over multiple cells, so that hot keys scale across cores.
+//
+// Reading a counter sums its cells, so the reads are more expensive than the
+// updates, and a read concurrent with updates may not reflect all of them.
+type CounterMap[K comparable] struct {
+	m       *ShardedMap[K, counter]
+	stripes int
+}
+
+// NewCounterMap returns a new CounterMap, where shards is the number of partitions
+// for this map.
+// If the shards argument is not positive, NewCounterMap will panic.
+func NewCounterMap[K comparable](shards int) *CounterMap[K] {
+	stripes := min(1<<bits.Len(uint(runtime.GOMAXPROCS(0)-1)), maxCounterStripes)
+	return &CounterMap[K]{
+		m:       NewShardedMap[K, counter](shards),
+		stripes: stripes,
+	}
+}
+
+// Add adds delta to the counter of the key.
+func (cm *CounterMap[K]) Add(key K, delta int64) {
+	c := cm.m.Get(key)
+	if c == nil {
+		c = cm.m.ComputeIfAbsent(key, func(_ K) *counter {
+			return &counter{}
+		})
+	}
+	c.add(delta, cm.stripes)
+}
+
+// Get returns the value of the counter of the key, or 0 if there is none.
+func (cm *CounterMap[K]) Get(key K) int64 {
+	if c := cm.m.Get(key); c != nil {
+		return c.sum()
+	}
+	return 0
+}
+
+// Inc increments the counter of the key by one.
+func (cm *CounterMap[K]) Inc(key K) {
+	cm.Add(key, 1)
+}
+
+// Reset sets the counter of the key to zero, returning its previous value.
+// The key is retained, so that the concurrent updates are not lost.
+func (cm *CounterMap[K]) Reset(key K) int64 {
+	if c := cm.m.Get(key); c != nil {
+		return c.reset()
+	}
+	return 0
+}
+
+// Size returns the number of counters in this map.
+func (cm *CounterMap[K]) Size() int {
+	return cm.m.Size()
+}
+
+// Snapshot returns a copy of the values of the counters in this map.
+// The values of different counters are not read atomically.
+func (cm *CounterMap[K]) Snapshot() map[K]int64 {
+	snapshot := make(map[K]int64)
+	for key, c := range cm.m.All() {
+		snapshot[key] = c.sum()
+	}
+	return snapshot
+}
+
+// TopN returns up to n entries with the highest counts, in descending order
+// of the counts. The order of the entries with equal counts is not specified.
+func (cm *CounterMap[K]) TopN(n int) []CounterEntry[K] {
+	if n < 1 {
+		return nil
+	}
+	top := make(counterHeap[K], 0, n)
+	for key, c := range cm.m.All() {
+		entry := CounterEntry[K]{Key: key, Count: c.sum()}
+		if len(top) < n {
+			heap.Push(&top, entry)
+		} else if entry.Count > top[0].Count {
+			top[0] = entry
+			heap.Fix(&top, 0)
+		}
+	}
+	slices.SortFunc(top, func(a, b CounterEntry[K]) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return top
+}
+
+// counter is a striped int64 counter. The cells are allocated on the first
+// contended update of the base value.
+type counter struct {
+	base  atomic.Int64
+	cells atomic.Pointer[[]counterCell]
+}
+
+// counterCell is a counter cell padded to a cache line to avoid false sharing.
+type counterCell struct {
+	atomic.Int64
+	_ [56]byte
+}
+
+func (c *counter) add(delta int64, stripes int) {
+	cells := c.cells.Load()
+	if cells == nil {
+		if stripes == 1 {
+			c.base.Add(delta)
+			return
+		}
+		value := c.base.Load()
+		if c.base.CompareAndSwap(value, value+delta) {
+			return
+		}
+		// the base value is contended
+		cells = c.expand(stripes)
+	}
+	(*cells)[rand.Uint32N(uint32(len(*cells)))].Add(delta)
+}
+
+// expand allocates the cells of the counter, unless already allocated
+// by a concurrent update, and returns them.
+func (c *counter) expand(stripes int) *[]counterCell {
+	cells := make([]counterCell, stripes)
+	if c.cells.CompareAndSwap(nil, &cells) {
+		return &cells
+	}
+	return c.cells.Load()
+}
+
+func (c *counter) sum() int64 {
+	value := c.base.Load()
+	if cells := c.cells.Load(); cells != nil {
+		for i := range *cells {
+			value += (*cells)[i].Load()
+		}
+	}
+	return value
+}
+
+func (c *counter) reset() int64 {
+	value := c.base.Swap(0)
+	if cells := c.cells.Load(); cells != nil {
+		for i := range *cells {
+			value += (*cells)[i].Swap(0)
+		}
+	}
+	return value
+}
+
+// counterHeap is a min-heap of counter entries ordered by count.
+type counterHeap[K comparable] []CounterEntry[K]
+
+func (h counterHeap[K]) Len() int           { return len(h) }
+func (h counterHeap[K]) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h counterHeap[K]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *counterHeap[K]) Push(x any) {
+	*h = append(*h, x.(CounterEntry[K]))
+}
+
+func (h *counterHeap[K]) Pop() any {
+	n := len(*h) - 1
+	entry := (*h)[n]
+	*h = (*h)[:n]
+	return entry
+}
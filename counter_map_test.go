@@ -0,0 +1,106 @@
+package async
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestCounterMap(t *testing.T) {
+	t.Parallel()
+
+	cm := NewCounterMap[string](4)
+	assert.Equal(t, cm.Get("a"), 0)
+	assert.Equal(t, cm.Reset("a"), 0)
+	assert.Equal(t, cm.Size(), 0)
+
+	cm.Inc("a")
+	cm.Inc("a")
+	cm.Add("b", 10)
+	cm.Add("b", -3)
+	cm.Add("c", 0)
+	assert.Equal(t, cm.Get("a"), 2)
+	assert.Equal(t, cm.Get("b"), 7)
+	assert.Equal(t, cm.Size(), 3)
+	assert.Equal(t, cm.Snapshot(), map[string]int64{"a": 2, "b": 7, "c": 0})
+
+	assert.Equal(t, cm.Reset("b"), 7)
+	assert.Equal(t, cm.Get("b"), 0)
+	assert.Equal(t, cm.Size(), 3)
+}
+
+func TestCounterMap_TopN(t *testing.T) {
+	t.Parallel()
+
+	cm := NewCounterMap[int](4)
+	assert.Equal(t, len(cm.TopN(3)), 0)
+	// the counts are a permutation of 1..10
+	for i := 1; i <= 10; i++ {
+		cm.Add(i, int64((i*7)%11))
+	}
+
+	assert.Equal(t, cm.TopN(3), []CounterEntry[int]{
+		{Key: 3, Count: 10},
+		{Key: 6, Count: 9},
+		{Key: 9, Count: 8},
+	})
+	assert.Equal(t, len(cm.TopN(20)), 10)
+	assert.Equal(t, cm.TopN(20)[9], CounterEntry[int]{Key: 8, Count: 1})
+	assert.IsNil(t, cm.TopN(0))
+}
+
+func TestCounterMap_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	cm := NewCounterMap[string](8)
+	const goroutines, increments = 8, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				cm.Inc("hot")
+				cm.Add(strconv.Itoa(j%10), 2)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, cm.Get("hot"), goroutines*increments)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, cm.Get(strconv.Itoa(i)), goroutines*increments/10*2)
+	}
+	assert.Equal(t, cm.TopN(1), []CounterEntry[string]{{Key: "hot", Count: goroutines * increments}})
+}
+
+func TestCounter_Striped(t *testing.T) {
+	t.Parallel()
+
+	var c counter
+	c.add(5, 4)
+	assert.IsNil(t, c.cells.Load())
+
+	// force the striped mode
+	cells := c.expand(4)
+	assert.Equal(t, len(*cells), 4)
+	assert.Same(t, c.expand(4), cells)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.add(1, 4)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, c.sum(), 4005)
+	assert.Equal(t, c.reset(), 4005)
+	assert.Equal(t, c.sum(), 0)
+}
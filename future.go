@@ -8,6 +8,10 @@ import (
 // Future represents a value which may or may not currently be available,
 // but will be available at some point, or an error if that value could
 // not be made available.
+//
+// Future is the read side of an asynchronous computation and may be
+// implemented outside of this package, e.g. to adapt third-party response
+// handles. Futures created by this package are completed using [Promise].
 type Future[T any] interface {
 
 	// Map creates a new Future by applying a function to the successful
@@ -33,10 +37,6 @@ type Future[T any] interface {
 	// RecoverWith handles any error that this Future might contain using
 	// another Future.
 	RecoverWith(Future[T]) Future[T]
-
-	// complete completes the Future with either a value or an error.
-	// It is used by [Promise] internally.
-	complete(T, error)
 }
 
 // futureImpl implements the Future interface.
@@ -51,13 +51,26 @@ type futureImpl[T any] struct {
 // Verify futureImpl satisfies the Future interface.
 var _ Future[any] = (*futureImpl[any])(nil)
 
-// newFuture returns a new Future.
-func newFuture[T any]() Future[T] {
+// newFuture returns a new incomplete futureImpl.
+func newFuture[T any]() *futureImpl[T] {
 	return &futureImpl[T]{
 		done: make(chan any, 1),
 	}
 }
 
+// newCompletedFuture returns a new Future, which is already completed with
+// either a value or an error. The result channel is not allocated.
+func newCompletedFuture[T any](value T, err error) Future[T] {
+	fut := &futureImpl[T]{
+		value: value,
+		err:   err,
+	}
+	// mark the result as accepted and ignore subsequent completions
+	fut.acceptOnce.Do(func() {})
+	fut.completeOnce.Do(func() {})
+	return fut
+}
+
 // accept blocks once, until the Future result is available.
 func (fut *futureImpl[T]) accept() {
 	fut.acceptOnce.Do(func() {
@@ -158,7 +171,7 @@ func (fut *futureImpl[T]) Recover(f func() (T, error)) Future[T] {
 }
 
 // RecoverWith handles any error that this Future might contain using
-// another Future.
+// another Future, which may be of any implementation.
 // Returns the result as a new Future.
 func (fut *futureImpl[T]) RecoverWith(rf Future[T]) Future[T] {
 	next := newFuture[T]()
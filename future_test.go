@@ -199,3 +199,46 @@ func TestFuture_GoroutineLeak(t *testing.T) {
 		t.Fatalf("numGoroutine is %d", numGoroutine)
 	}
 }
+
+func TestFuture_ExternalImplementation(t *testing.T) {
+	external := &stubFuture[int]{Future: FutureOf(0), value: 2}
+	failed := &stubFuture[int]{Future: FutureOf(0), err: errors.New("stub error")}
+
+	futRes, _ := FutureSeq([]Future[int]{FutureOf(1), external, failed}).Join()
+	assert.Equal(t, []any{1, 2, failed.err}, futRes)
+
+	res, err := FailedFuture[int](errors.New("error")).RecoverWith(external).Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 2, res)
+
+	res, err = FutureOf(1).FlatMap(func(_ int) (Future[int], error) {
+		return external, nil
+	}).Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 2, res)
+
+	p := NewPromise[int]()
+	p.CompleteWith(failed)
+	_, err = p.Future().Join()
+	assert.ErrorIs(t, err, failed.err)
+
+	res, err = FutureFirstCompletedOf[int](external).Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 2, res)
+}
+
+// stubFuture is a Future implementation defined outside the package
+// internals, overriding the result of the embedded Future.
+type stubFuture[T any] struct {
+	Future[T]
+	value T
+	err   error
+}
+
+func (f *stubFuture[T]) Join() (T, error) {
+	return f.value, f.err
+}
+
+func (f *stubFuture[T]) Get(_ context.Context) (T, error) {
+	return f.value, f.err
+}
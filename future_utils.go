@@ -1,11 +1,53 @@
 package async
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 )
 
-// FutureSeq reduces many Futures into a single Future.
+// ErrChannelClosed is returned by a Future created using [FutureFromChan]
+// when the channel is closed before a value is received.
+var ErrChannelClosed = errors.New("async: channel closed before a value was received")
+
+// FutureOf returns a Future which is already completed with the given value.
+func FutureOf[T any](value T) Future[T] {
+	return newCompletedFuture(value, nil)
+}
+
+// FailedFuture returns a Future which is already failed with the given error.
+func FailedFuture[T any](err error) Future[T] {
+	var zero T
+	return newCompletedFuture(zero, err)
+}
+
+// FutureFromChan returns a Future which will be completed with the first value
+// received from the channel, or failed with ErrChannelClosed if the channel is
+// closed before a value is received.
+func FutureFromChan[T any](ch <-chan T) Future[T] {
+	next := newFuture[T]()
+	go func() {
+		value, ok := <-ch
+		if ok {
+			next.complete(value, nil)
+		} else {
+			var zero T
+			next.complete(zero, ErrChannelClosed)
+		}
+	}()
+	return next
+}
+
+// FutureFunc starts executing the function using a goroutine and returns
+// a Future which will be completed with its result or error. If ctx is done
+// before the function returns, the Future is completed with the context error.
+func FutureFunc[T any](ctx context.Context, f func(context.Context) (T, error)) Future[T] {
+	return NewTaskContext(f).CallContext(ctx)
+}
+
+// FutureSeq reduces many Futures, which may be of any implementation,
+// into a single Future.
 // The resulting array may contain both T values and errors.
 func FutureSeq[T any](futures []Future[T]) Future[[]any] {
 	next := newFuture[[]any]()
@@ -46,8 +88,7 @@ func FutureTimer[T any](d time.Duration) Future[T] {
 	go func() {
 		<-time.After(d)
 		var zero T
-		next.(*futureImpl[T]).
-			complete(zero, fmt.Errorf("future timeout after %s", d))
+		next.complete(zero, fmt.Errorf("future timeout after %s", d))
 	}()
 	return next
 }
@@ -0,0 +1,81 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestFutureOf(t *testing.T) {
+	future := FutureOf(1)
+	result, err := future.Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 1, result)
+
+	result, err = future.Get(context.Background())
+	assert.IsNil(t, err)
+	assert.Equal(t, 1, result)
+
+	result, err = future.Map(func(v int) (int, error) {
+		return v + 1, nil
+	}).Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 2, result)
+}
+
+func TestFutureOf_Allocations(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = FutureOf(1).Join()
+	})
+	assert.Equal(t, 1.0, allocs)
+}
+
+func TestFailedFuture(t *testing.T) {
+	err := errors.New("error")
+	future := FailedFuture[int](err)
+	result, resultErr := future.Join()
+	assert.ErrorIs(t, resultErr, err)
+	assert.Equal(t, 0, result)
+
+	result, resultErr = future.Recover(func() (int, error) {
+		return 1, nil
+	}).Join()
+	assert.IsNil(t, resultErr)
+	assert.Equal(t, 1, result)
+}
+
+func TestFutureFromChan(t *testing.T) {
+	ch := make(chan string)
+	future := FutureFromChan(ch)
+	go func() {
+		time.Sleep(time.Millisecond)
+		ch <- "ok"
+	}()
+	result, err := future.Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, "ok", result)
+
+	closed := make(chan string)
+	close(closed)
+	_, err = FutureFromChan(closed).Join()
+	assert.ErrorIs(t, err, ErrChannelClosed)
+}
+
+func TestFutureFunc(t *testing.T) {
+	result, err := FutureFunc(context.Background(), func(_ context.Context) (int, error) {
+		return 1, nil
+	}).Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 1, result)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FutureFunc(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 1, nil
+	}).Join()
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,14 @@
+package async
+
+import "hash/maphash"
+
+// newHashFunc returns a hash function for the keys of type K, based on
+// the hash/maphash package. Each returned function uses a new random seed,
+// so that hash values are not predictable across maps, which makes them
+// resistant to hash-flooding attacks.
+func newHashFunc[K comparable]() func(K) uint64 {
+	seed := maphash.MakeSeed()
+	return func(key K) uint64 {
+		return comparableHash(seed, key)
+	}
+}
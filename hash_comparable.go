@@ -0,0 +1,11 @@
+//go:build go1.24
+
+package async
+
+import "hash/maphash"
+
+// comparableHash returns the hash of the key with the given seed.
+// Equal keys have equal hashes, as defined by the == operator.
+func comparableHash[K comparable](seed maphash.Seed, key K) uint64 {
+	return maphash.Comparable(seed, key)
+}
@@ -0,0 +1,140 @@
+//go:build !go1.24
+
+package async
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// comparableHash returns the hash of the key with the given seed.
+// Keys of the basic types are hashed directly, while other keys are hashed
+// using reflection, as maphash.Comparable is not available before Go 1.24.
+func comparableHash[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return hashUint64(seed, uint64(k))
+	case int8:
+		return hashUint64(seed, uint64(k))
+	case int16:
+		return hashUint64(seed, uint64(k))
+	case int32:
+		return hashUint64(seed, uint64(k))
+	case int64:
+		return hashUint64(seed, uint64(k))
+	case uint:
+		return hashUint64(seed, uint64(k))
+	case uint8:
+		return hashUint64(seed, uint64(k))
+	case uint16:
+		return hashUint64(seed, uint64(k))
+	case uint32:
+		return hashUint64(seed, uint64(k))
+	case uint64:
+		return hashUint64(seed, k)
+	case uintptr:
+		return hashUint64(seed, uint64(k))
+	case float32:
+		if k == 0 {
+			k = 0 // -0 == +0
+		}
+		return hashUint64(seed, uint64(math.Float32bits(k)))
+	case float64:
+		if k == 0 {
+			k = 0 // -0 == +0
+		}
+		return hashUint64(seed, math.Float64bits(k))
+	case bool:
+		if k {
+			return hashUint64(seed, 1)
+		}
+		return hashUint64(seed, 0)
+	default:
+		return reflectHash(seed, key)
+	}
+}
+
+func hashUint64(seed maphash.Seed, value uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	return maphash.Bytes(seed, buf[:])
+}
+
+// reflectHash returns the hash of the key with the given seed, consistently
+// with the == operator, as maphash.Comparable does. Pointers and channels
+// are hashed by identity, interfaces by their dynamic type and value, and
+// structs and arrays by their fields and elements.
+func reflectHash(seed maphash.Seed, key any) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	writeHash(&h, reflect.ValueOf(key))
+	return h.Sum64()
+}
+
+// writeHash writes the value to the hash.
+func writeHash(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		// a nil interface
+		_ = h.WriteByte(0)
+	case reflect.Bool:
+		if v.Bool() {
+			_ = h.WriteByte(1)
+		} else {
+			_ = h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat64(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		writeFloat64(h, real(v.Complex()))
+		writeFloat64(h, imag(v.Complex()))
+	case reflect.String:
+		writeUint64(h, uint64(v.Len()))
+		_, _ = h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint64(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			_ = h.WriteByte(0)
+			return
+		}
+		_ = h.WriteByte(1)
+		_, _ = h.WriteString(v.Elem().Type().String())
+		writeHash(h, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// blank fields are not compared
+			if v.Type().Field(i).Name != "_" {
+				writeHash(h, v.Field(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeHash(h, v.Index(i))
+		}
+	default:
+		panic(fmt.Sprintf("unhashable type: %s", v.Type()))
+	}
+}
+
+func writeUint64(h *maphash.Hash, value uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	_, _ = h.Write(buf[:])
+}
+
+func writeFloat64(h *maphash.Hash, value float64) {
+	if value == 0 {
+		value = 0 // -0 == +0
+	}
+	writeUint64(h, math.Float64bits(value))
+}
@@ -0,0 +1,57 @@
+//go:build !go1.24
+
+package async
+
+import (
+	"hash/maphash"
+	"testing"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+type reflectHashTestKey struct {
+	node  *hashTestKey
+	ch    chan int
+	value any
+	pair  [2]float64
+	_     int
+}
+
+func TestReflectHash(t *testing.T) {
+	seed := maphash.MakeSeed()
+	hash := func(key any) uint64 {
+		return reflectHash(seed, key)
+	}
+
+	// pointers are hashed by identity, not by the pointed value
+	node1, node2 := &hashTestKey{1, "a", true}, &hashTestKey{1, "a", true}
+	hash1 := hash(node1)
+	node1.name = "b"
+	assert.Equal(t, hash(node1), hash1)
+	assert.NotEqual(t, hash(node1), hash(node2))
+
+	ch := make(chan int)
+	assert.Equal(t, hash(ch), hash(ch))
+	assert.NotEqual(t, hash(ch), hash(make(chan int)))
+
+	negativeZero := 0.0
+	negativeZero = -negativeZero
+	key := reflectHashTestKey{node1, ch, 1, [2]float64{0, 1}, 0}
+	equalKey := reflectHashTestKey{node1, ch, 1, [2]float64{negativeZero, 1}, 1}
+	assert.Equal(t, key == equalKey, true)
+	assert.Equal(t, hash(key), hash(equalKey))
+
+	for _, other := range []reflectHashTestKey{
+		{node2, ch, 1, [2]float64{0, 1}, 0},
+		{node1, nil, 1, [2]float64{0, 1}, 0},
+		{node1, ch, int64(1), [2]float64{0, 1}, 0},
+		{node1, ch, nil, [2]float64{0, 1}, 0},
+		{node1, ch, 1, [2]float64{1, 0}, 0},
+	} {
+		assert.NotEqual(t, hash(key), hash(other))
+	}
+
+	assert.Equal(t, hash(nil), hash(nil))
+	assert.Equal(t, hash(any(node1)), hash(node1))
+	assert.Panics(t, func() { hash(reflectHashTestKey{value: []int{1}}) })
+}
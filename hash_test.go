@@ -0,0 +1,69 @@
+package async
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/reugn/async/internal/assert"
+	"github.com/reugn/async/internal/ptr"
+)
+
+type hashTestKey struct {
+	id   uint64
+	name string
+	ok   bool
+}
+
+func TestHashFunc_Equality(t *testing.T) {
+	hash := newHashFunc[hashTestKey]()
+	assert.Equal(t, hash(hashTestKey{1, "a", true}), hash(hashTestKey{1, "a", true}))
+	assert.NotEqual(t, hash(hashTestKey{1, "a", true}), hash(hashTestKey{1, "b", true}))
+
+	value := 1
+	pointerHash := newHashFunc[*int]()
+	assert.Equal(t, pointerHash(&value), pointerHash(&value))
+
+	floatHash := newHashFunc[float64]()
+	negativeZero := 0.0
+	negativeZero = -negativeZero
+	assert.Equal(t, floatHash(0), floatHash(negativeZero))
+}
+
+func TestHashFunc_Seed(t *testing.T) {
+	hash1, hash2 := newHashFunc[string](), newHashFunc[string]()
+	var equal int
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		if hash1(key) == hash2(key) {
+			equal++
+		}
+	}
+	assert.Equal(t, equal < 100, true)
+}
+
+func TestHashFunc_Distribution(t *testing.T) {
+	hash := newHashFunc[hashTestKey]()
+	const shards, keys = 16, 16000
+	counts := make([]int, shards)
+	for i := 0; i < keys; i++ {
+		counts[hash(hashTestKey{uint64(i), strconv.Itoa(i), i%2 == 0})%shards]++
+	}
+	for shard, count := range counts {
+		if count < keys/shards/2 || count > keys/shards*2 {
+			t.Fatalf("unbalanced shard %d: %d keys", shard, count)
+		}
+	}
+}
+
+func TestShardedMap_StructKeys(t *testing.T) {
+	m := NewShardedMap[hashTestKey, int](8)
+	for i := 0; i < 100; i++ {
+		m.Put(hashTestKey{uint64(i), strconv.Itoa(i), true}, ptr.Of(i))
+	}
+	assert.Equal(t, m.Size(), 100)
+	assert.Equal(t, *m.Get(hashTestKey{42, "42", true}), 42)
+	assert.IsNil(t, m.Get(hashTestKey{42, "42", false}))
+	for _, shard := range m.Stats() {
+		assert.NotEqual(t, shard.Size, 0)
+	}
+}
@@ -1,18 +1,53 @@
 package async
 
-import "iter"
+import (
+	"iter"
+	"slices"
+)
 
 // A Map is an object that maps keys to values.
+//
+// The read-modify-write operations (Compute, ComputeIfPresent, Merge,
+// PutIfAbsent, Replace, CompareAndSwap and CompareAndDelete) are performed
+// atomically with respect to other operations on the same key. Depending on
+// the implementation, the remapping functions may be called more than once
+// under contention, so they should be free of side effects.
+//
+// The atomicity of the bulk operations (PutAll, RemoveAll, RemoveIf and
+// ReplaceAll) and the consistency of Snapshot are documented by each
+// implementation.
 type Map[K comparable, V any] interface {
 
 	// Clear removes all of the mappings from this map.
 	Clear()
 
+	// CompareAndDelete removes the mapping for the specified key only if it
+	// is currently mapped to oldValue (compared by pointer).
+	// It returns true if the mapping was removed.
+	CompareAndDelete(key K, oldValue *V) bool
+
+	// CompareAndSwap replaces the value for the specified key with newValue
+	// only if it is currently mapped to oldValue (compared by pointer).
+	// It returns true if the value was replaced.
+	CompareAndSwap(key K, oldValue, newValue *V) bool
+
+	// Compute attempts to compute a mapping for the specified key and its
+	// current value, or nil if there is no current mapping, and enters it
+	// into the map. If the remapping function returns nil, the mapping is
+	// removed. It returns the new value or nil if none.
+	Compute(key K, remappingFunction func(K, *V) *V) *V
+
 	// ComputeIfAbsent attempts to compute a value using the given mapping
 	// function and enters it into the map, if the specified key is not
 	// already associated with a value.
 	ComputeIfAbsent(key K, mappingFunction func(K) *V) *V
 
+	// ComputeIfPresent attempts to compute a new mapping given the key and
+	// its current value, if the specified key is associated with a value.
+	// If the remapping function returns nil, the mapping is removed.
+	// It returns the new value or nil if none.
+	ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V
+
 	// ContainsKey returns true if this map contains a mapping for the
 	// specified key.
 	ContainsKey(key K) bool
@@ -31,16 +66,53 @@ type Map[K comparable, V any] interface {
 	// KeySet returns a slice of the keys contained in this map.
 	KeySet() []K
 
+	// Merge associates the specified key with the given value if it is not
+	// already associated with a value. Otherwise, replaces the value with the
+	// result of the remapping function applied to the current and the given
+	// values, or removes the mapping if the result is nil.
+	// It returns the new value or nil if none.
+	Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V
+
 	// Put associates the specified value with the specified key in this map.
 	Put(key K, value *V)
 
+	// PutAll copies all of the key-value pairs from the given sequence
+	// to this map.
+	PutAll(entries iter.Seq2[K, *V])
+
+	// PutIfAbsent associates the specified key with the given value if it is
+	// not already associated with a value. It returns the current value,
+	// or nil if the given value was stored.
+	PutIfAbsent(key K, value *V) *V
+
 	// Remove removes the mapping for a key from this map if it is present,
 	// returning the previous value or nil if none.
 	Remove(key K) *V
 
+	// RemoveAll removes the mappings for all of the given keys from this
+	// map. It returns the number of removed mappings.
+	RemoveAll(keys iter.Seq[K]) int
+
+	// RemoveIf removes all of the mappings that satisfy the given predicate.
+	// It returns the number of removed mappings.
+	RemoveIf(predicate func(K, *V) bool) int
+
+	// Replace replaces the value for the specified key only if it is
+	// currently mapped to some value. It returns the previous value,
+	// or nil if there was no mapping for the key.
+	Replace(key K, value *V) *V
+
+	// ReplaceAll replaces the value of each mapping with the result of the
+	// given function applied to the mapping. If the function returns nil,
+	// the mapping is removed.
+	ReplaceAll(function func(K, *V) *V)
+
 	// Size returns the number of key-value mappings in this map.
 	Size() int
 
+	// Snapshot returns an immutable copy of the mappings in this map.
+	Snapshot() *MapSnapshot[K, V]
+
 	// Values returns a slice of the values contained in this map.
 	Values() []*V
 
@@ -48,3 +120,95 @@ type Map[K comparable, V any] interface {
 	// The order of the pairs is not specified.
 	All() iter.Seq2[K, *V]
 }
+
+// MapSnapshot is an immutable copy of the mappings of a Map, taken at
+// a point in time. The values are shared with the map, so the pointed
+// values should not be mutated in place. The snapshot preserves the
+// iteration order of the source map.
+type MapSnapshot[K comparable, V any] struct {
+	keys   []K
+	values []*V
+	index  map[K]int
+}
+
+func newMapSnapshot[K comparable, V any](size int) *MapSnapshot[K, V] {
+	return &MapSnapshot[K, V]{
+		keys:   make([]K, 0, size),
+		values: make([]*V, 0, size),
+		index:  make(map[K]int, size),
+	}
+}
+
+func (s *MapSnapshot[K, V]) add(key K, value *V) {
+	if i, ok := s.index[key]; ok {
+		s.values[i] = value
+		return
+	}
+	s.index[key] = len(s.keys)
+	s.keys = append(s.keys, key)
+	s.values = append(s.values, value)
+}
+
+// ContainsKey returns true if the snapshot contains a mapping for the
+// specified key.
+func (s *MapSnapshot[K, V]) ContainsKey(key K) bool {
+	_, ok := s.index[key]
+	return ok
+}
+
+// Get returns the value to which the specified key is mapped, or nil if
+// the snapshot contains no mapping for the key.
+func (s *MapSnapshot[K, V]) Get(key K) *V {
+	if i, ok := s.index[key]; ok {
+		return s.values[i]
+	}
+	return nil
+}
+
+// IsEmpty returns true if the snapshot contains no key-value mappings.
+func (s *MapSnapshot[K, V]) IsEmpty() bool {
+	return len(s.keys) == 0
+}
+
+// KeySet returns a slice of the keys contained in the snapshot.
+func (s *MapSnapshot[K, V]) KeySet() []K {
+	return slices.Clone(s.keys)
+}
+
+// Size returns the number of key-value mappings in the snapshot.
+func (s *MapSnapshot[K, V]) Size() int {
+	return len(s.keys)
+}
+
+// Values returns a slice of the values contained in the snapshot.
+func (s *MapSnapshot[K, V]) Values() []*V {
+	return slices.Clone(s.values)
+}
+
+// All returns an iterator of all key-value pairs in the snapshot,
+// in the iteration order of the source map.
+func (s *MapSnapshot[K, V]) All() iter.Seq2[K, *V] {
+	return func(yield func(K, *V) bool) {
+		for i, key := range s.keys {
+			if !yield(key, s.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// mapEntry is a key-value pair of a Map.
+type mapEntry[K comparable, V any] struct {
+	key   K
+	value *V
+}
+
+// collectEntries collects the key-value pairs of the sequence, so that
+// the sequence is not iterated while holding a map lock.
+func collectEntries[K comparable, V any](entries iter.Seq2[K, *V]) []mapEntry[K, V] {
+	var collected []mapEntry[K, V]
+	for key, value := range entries {
+		collected = append(collected, mapEntry[K, V]{key, value})
+	}
+	return collected
+}
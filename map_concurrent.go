@@ -4,7 +4,6 @@ import (
 	"iter"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 // ConcurrentMap implements the async.Map interface in a thread-safe manner
@@ -16,44 +15,140 @@ import (
 // or (2) when multiple goroutines read, write, and overwrite entries for disjoint
 // sets of keys. In these two cases, use of a sync.Map may significantly reduce lock
 // contention compared to a Go map paired with a separate sync.Mutex or sync.RWMutex.
+//
+// Write operations share a read lock, which is held exclusively by Clear,
+// Size and Snapshot. This makes Clear linearizable with respect to other
+// operations, makes Size exact and Snapshot consistent. Reads never acquire
+// the lock, and the user functions are called without holding it, so they
+// may access the map.
+//
+// The bulk operations are applied entry by entry, each entry atomically.
 type ConcurrentMap[K comparable, V any] struct {
-	m        *atomic.Pointer[sync.Map]
-	size     atomic.Int64
-	clearing atomic.Bool
+	mtx     sync.RWMutex
+	m       sync.Map
+	pending sync.Map // in-flight ComputeIfAbsent calls: K -> Future[*V]
+	size    atomic.Int64
 }
 
 var _ Map[int, any] = (*ConcurrentMap[int, any])(nil)
 
 // NewConcurrentMap returns a new ConcurrentMap instance.
 func NewConcurrentMap[K comparable, V any]() *ConcurrentMap[K, V] {
-	var underlying atomic.Pointer[sync.Map]
-	underlying.Store(&sync.Map{})
-	return &ConcurrentMap[K, V]{
-		m: &underlying,
-	}
+	return &ConcurrentMap[K, V]{}
 }
 
 // Clear removes all of the mappings from this map.
 func (cm *ConcurrentMap[K, V]) Clear() {
-	cm.clearing.Store(true)
-	defer cm.clearing.Store(false)
-	cm.m.Store(&sync.Map{})
+	cm.mtx.Lock()
+	defer cm.mtx.Unlock()
+	cm.m.Clear()
 	cm.size.Store(0)
 }
 
+// CompareAndDelete removes the mapping for the specified key only if it
+// is currently mapped to oldValue (compared by pointer).
+// It returns true if the mapping was removed.
+func (cm *ConcurrentMap[K, V]) CompareAndDelete(key K, oldValue *V) bool {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	if cm.m.CompareAndDelete(key, oldValue) {
+		cm.size.Add(-1)
+		return true
+	}
+	return false
+}
+
+// CompareAndSwap replaces the value for the specified key with newValue
+// only if it is currently mapped to oldValue (compared by pointer).
+// It returns true if the value was replaced.
+func (cm *ConcurrentMap[K, V]) CompareAndSwap(key K, oldValue, newValue *V) bool {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	return cm.m.CompareAndSwap(key, oldValue, newValue)
+}
+
+// Compute attempts to compute a mapping for the specified key and its
+// current value, or nil if there is no current mapping, and enters it
+// into the map. If the remapping function returns nil, the mapping is
+// removed. It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (cm *ConcurrentMap[K, V]) Compute(key K, remappingFunction func(K, *V) *V) *V {
+	for {
+		current, loaded := cm.m.Load(key)
+		if !loaded {
+			value := remappingFunction(key, nil)
+			if value == nil {
+				return nil
+			}
+			if _, loaded = cm.loadOrStore(key, value); !loaded {
+				return value
+			}
+			continue
+		}
+		if value, ok := cm.replaceLoaded(key, current,
+			remappingFunction(key, current.(*V))); ok {
+			return value
+		}
+	}
+}
+
 // ComputeIfAbsent attempts to compute a value using the given mapping
 // function and enters it into the map, if the specified key is not
 // already associated with a value.
+//
+// The mapping function is called at most once per absent key: concurrent
+// callers for the same key wait for the in-flight computation to complete,
+// while operations on other keys are not blocked. Readers do not observe
+// the key until its value is computed.
 func (cm *ConcurrentMap[K, V]) ComputeIfAbsent(key K, mappingFunction func(K) *V) *V {
-	value := cm.Get(key)
-	if value == nil {
-		computed, loaded := cm.smap().LoadOrStore(key, mappingFunction(key))
+	for {
+		if value := cm.Get(key); value != nil {
+			return value
+		}
+		promise := NewPromise[*V]()
+		pending, loaded := cm.pending.LoadOrStore(key, promise.Future())
 		if !loaded {
-			cm.size.Add(1)
+			return cm.computeAbsent(key, mappingFunction, promise)
+		}
+		// wait for the in-flight computation and retry
+		_, _ = pending.(Future[*V]).Join()
+	}
+}
+
+// computeAbsent computes and stores a value for the key, while holding
+// the exclusive pending computation slot for the key. The slot is released
+// when the computation is done, even if the mapping function panics.
+func (cm *ConcurrentMap[K, V]) computeAbsent(key K, mappingFunction func(K) *V,
+	promise Promise[*V]) (value *V) {
+	defer func() {
+		cm.pending.Delete(key)
+		promise.Success(value)
+	}()
+	// the key may have been stored since the last check
+	if value = cm.Get(key); value != nil {
+		return value
+	}
+	value = mappingFunction(key)
+	computed, _ := cm.loadOrStore(key, value)
+	return computed
+}
+
+// ComputeIfPresent attempts to compute a new mapping given the key and
+// its current value, if the specified key is associated with a value.
+// If the remapping function returns nil, the mapping is removed.
+// It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (cm *ConcurrentMap[K, V]) ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	for {
+		current, loaded := cm.m.Load(key)
+		if !loaded {
+			return nil
+		}
+		if value, ok := cm.replaceLoaded(key, current,
+			remappingFunction(key, current.(*V))); ok {
+			return value
 		}
-		return computed.(*V)
 	}
-	return value
 }
 
 // ContainsKey returns true if this map contains a mapping for the
@@ -65,7 +160,7 @@ func (cm *ConcurrentMap[K, V]) ContainsKey(key K) bool {
 // Get returns the value to which the specified key is mapped, or nil if
 // this map contains no mapping for the key.
 func (cm *ConcurrentMap[K, V]) Get(key K) *V {
-	value, ok := cm.smap().Load(key)
+	value, ok := cm.m.Load(key)
 	if !ok {
 		return nil
 	}
@@ -75,7 +170,7 @@ func (cm *ConcurrentMap[K, V]) Get(key K) *V {
 // GetOrDefault returns the value to which the specified key is mapped, or
 // defaultValue if this map contains no mapping for the key.
 func (cm *ConcurrentMap[K, V]) GetOrDefault(key K, defaultValue *V) *V {
-	value, ok := cm.smap().Load(key)
+	value, ok := cm.m.Load(key)
 	if !ok {
 		return defaultValue
 	}
@@ -89,46 +184,148 @@ func (cm *ConcurrentMap[K, V]) IsEmpty() bool {
 
 // KeySet returns a slice of the keys contained in this map.
 func (cm *ConcurrentMap[K, V]) KeySet() []K {
-	keys := make([]K, 0, cm.Size())
-	cm.smap().Range(func(key any, _ any) bool {
+	keys := make([]K, 0, cm.sizeHint())
+	cm.m.Range(func(key any, _ any) bool {
 		keys = append(keys, key.(K))
 		return true
 	})
 	return keys
 }
 
+// Merge associates the specified key with the given value if it is not
+// already associated with a value. Otherwise, replaces the value with the
+// result of the remapping function applied to the current and the given
+// values, or removes the mapping if the result is nil.
+// It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (cm *ConcurrentMap[K, V]) Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	for {
+		current, loaded := cm.loadOrStore(key, value)
+		if !loaded {
+			return value
+		}
+		if merged, ok := cm.replaceLoaded(key, current,
+			remappingFunction(current, value)); ok {
+			return merged
+		}
+	}
+}
+
 // Put associates the specified value with the specified key in this map.
 func (cm *ConcurrentMap[K, V]) Put(key K, value *V) {
-	_, loaded := cm.smap().Swap(key, value)
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	_, loaded := cm.m.Swap(key, value)
 	if !loaded {
 		cm.size.Add(1)
 	}
 }
 
+// PutAll copies all of the key-value pairs from the given sequence
+// to this map.
+func (cm *ConcurrentMap[K, V]) PutAll(entries iter.Seq2[K, *V]) {
+	for key, value := range entries {
+		cm.Put(key, value)
+	}
+}
+
+// PutIfAbsent associates the specified key with the given value if it is
+// not already associated with a value. It returns the current value,
+// or nil if the given value was stored.
+func (cm *ConcurrentMap[K, V]) PutIfAbsent(key K, value *V) *V {
+	if current, loaded := cm.loadOrStore(key, value); loaded {
+		return current
+	}
+	return nil
+}
+
 // Remove removes the mapping for a key from this map if it is present,
 // returning the previous value or nil if none.
 func (cm *ConcurrentMap[K, V]) Remove(key K) *V {
-	value, loaded := cm.smap().LoadAndDelete(key)
-	if !loaded {
-		return nil
+	value, _ := cm.loadAndDelete(key)
+	return value
+}
+
+// RemoveAll removes the mappings for all of the given keys from this
+// map. It returns the number of removed mappings.
+func (cm *ConcurrentMap[K, V]) RemoveAll(keys iter.Seq[K]) int {
+	var removed int
+	for key := range keys {
+		if _, loaded := cm.loadAndDelete(key); loaded {
+			removed++
+		}
 	}
-	cm.size.Add(-1)
-	return value.(*V)
+	return removed
+}
+
+// RemoveIf removes all of the mappings that satisfy the given predicate.
+// It returns the number of removed mappings.
+// A mapping is not removed if its value changes after the predicate is
+// evaluated.
+func (cm *ConcurrentMap[K, V]) RemoveIf(predicate func(K, *V) bool) int {
+	var removed int
+	cm.m.Range(func(key, value any) bool {
+		if predicate(key.(K), value.(*V)) && cm.CompareAndDelete(key.(K), value.(*V)) {
+			removed++
+		}
+		return true
+	})
+	return removed
+}
+
+// Replace replaces the value for the specified key only if it is
+// currently mapped to some value. It returns the previous value,
+// or nil if there was no mapping for the key.
+func (cm *ConcurrentMap[K, V]) Replace(key K, value *V) *V {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	for {
+		current, loaded := cm.m.Load(key)
+		if !loaded {
+			return nil
+		}
+		if cm.m.CompareAndSwap(key, current, value) {
+			return current.(*V)
+		}
+	}
+}
+
+// ReplaceAll replaces the value of each mapping with the result of the
+// given function applied to the mapping. If the function returns nil,
+// the mapping is removed.
+// The function may be called multiple times under contention.
+func (cm *ConcurrentMap[K, V]) ReplaceAll(function func(K, *V) *V) {
+	cm.m.Range(func(key, _ any) bool {
+		cm.ComputeIfPresent(key.(K), function)
+		return true
+	})
 }
 
 // Size returns the number of key-value mappings in this map.
+// The write operations are blocked while the size is read.
 func (cm *ConcurrentMap[K, V]) Size() int {
-	size := cm.size.Load()
-	if size > 0 {
-		return int(size)
-	}
-	return 0
+	cm.mtx.Lock()
+	defer cm.mtx.Unlock()
+	return int(cm.size.Load())
+}
+
+// Snapshot returns an immutable copy of the mappings in this map.
+// The write operations are blocked while the copy is taken.
+func (cm *ConcurrentMap[K, V]) Snapshot() *MapSnapshot[K, V] {
+	cm.mtx.Lock()
+	defer cm.mtx.Unlock()
+	snapshot := newMapSnapshot[K, V](int(cm.size.Load()))
+	cm.m.Range(func(key, value any) bool {
+		snapshot.add(key.(K), value.(*V))
+		return true
+	})
+	return snapshot
 }
 
 // Values returns a slice of the values contained in this map.
 func (cm *ConcurrentMap[K, V]) Values() []*V {
-	values := make([]*V, 0, cm.Size())
-	cm.smap().Range(func(_ any, value any) bool {
+	values := make([]*V, 0, cm.sizeHint())
+	cm.m.Range(func(_ any, value any) bool {
 		values = append(values, value.(*V))
 		return true
 	})
@@ -139,19 +336,56 @@ func (cm *ConcurrentMap[K, V]) Values() []*V {
 // The order of the pairs is not specified.
 func (cm *ConcurrentMap[K, V]) All() iter.Seq2[K, *V] {
 	return func(yield func(K, *V) bool) {
-		cm.smap().Range(func(key, value any) bool {
+		cm.m.Range(func(key, value any) bool {
 			return yield(key.(K), value.(*V))
 		})
 	}
 }
 
-//nolint:staticcheck
-func (cm *ConcurrentMap[K, V]) smap() *sync.Map {
-	for {
-		if !cm.clearing.Load() {
-			break
+// loadOrStore stores the value for the key if it is absent. It returns
+// the current value and true if the key was present, or the given value
+// and false if it was stored.
+func (cm *ConcurrentMap[K, V]) loadOrStore(key K, value *V) (*V, bool) {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	current, loaded := cm.m.LoadOrStore(key, value)
+	if !loaded {
+		cm.size.Add(1)
+	}
+	return current.(*V), loaded
+}
+
+// loadAndDelete removes the mapping for the key, returning the previous
+// value and whether it was present.
+func (cm *ConcurrentMap[K, V]) loadAndDelete(key K) (*V, bool) {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	value, loaded := cm.m.LoadAndDelete(key)
+	if !loaded {
+		return nil, false
+	}
+	cm.size.Add(-1)
+	return value.(*V), true
+}
+
+// sizeHint returns the approximate number of mappings without blocking
+// the write operations.
+func (cm *ConcurrentMap[K, V]) sizeHint() int {
+	return int(max(cm.size.Load(), 0))
+}
+
+// replaceLoaded atomically replaces the loaded current value for the key
+// with the given value, or removes the mapping if the value is nil.
+// It returns false if the current value was changed concurrently.
+func (cm *ConcurrentMap[K, V]) replaceLoaded(key K, current any, value *V) (*V, bool) {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	if value == nil {
+		if cm.m.CompareAndDelete(key, current) {
+			cm.size.Add(-1)
+			return nil, true
 		}
-		time.Sleep(time.Nanosecond)
+		return nil, false
 	}
-	return cm.m.Load()
+	return value, cm.m.CompareAndSwap(key, current, value)
 }
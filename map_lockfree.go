@@ -0,0 +1,696 @@
+package async
+
+import (
+	"iter"
+	"sync/atomic"
+)
+
+const (
+	// lockFreeInitialBuckets is the number of buckets in a new table.
+	lockFreeInitialBuckets = 16
+	// lockFreeLoadFactor is the average number of entries per bucket
+	// which triggers the table growth.
+	lockFreeLoadFactor = 2
+	// lockFreeMigrationChunk is the number of buckets migrated by a write
+	// operation while the table is being resized.
+	lockFreeMigrationChunk = 8
+)
+
+// LockFreeMap implements the async.Map interface using a hash table with
+// lock-free operations. A LockFreeMap must not be copied.
+//
+// Each bucket of the table is an immutable slice of entries, which is
+// replaced as a whole using a compare-and-swap operation. Unlike with
+// ConcurrentMap, keys and values are stored with their static types,
+// without boxing them into interfaces.
+//
+// The table doubles in size when the average number of entries per bucket
+// exceeds the load factor. The resizing is incremental: buckets are frozen
+// and split into the new table one at a time, and every write operation
+// helps to migrate a chunk of buckets while the resizing is in progress.
+// Readers are never blocked, and operations on a bucket being migrated
+// proceed in the new table.
+//
+// Snapshot copies the table into a new table of the same size using the
+// same migration, except that all of the buckets are frozen before any of
+// them is migrated. The frozen buckets then hold a consistent copy of the
+// mappings, while writers help to freeze the remaining buckets instead of
+// being blocked.
+//
+// The bulk operations are applied entry by entry, each entry atomically.
+type LockFreeMap[K comparable, V any] struct {
+	table    atomic.Pointer[lockFreeTable[K, V]]
+	size     atomic.Int64
+	hashFunc func(K) uint64
+}
+
+var _ Map[int, any] = (*LockFreeMap[int, any])(nil)
+
+// NewLockFreeMap returns a new LockFreeMap. It uses a hash/maphash based
+// function with a random per-map seed to calculate the bucket number for a key.
+func NewLockFreeMap[K comparable, V any]() *LockFreeMap[K, V] {
+	return NewLockFreeMapWithHash[K, V](newHashFunc[K]())
+}
+
+// NewLockFreeMapWithHash returns a new LockFreeMap, where hashFunc is
+// a custom hash function to calculate the bucket number for a key.
+// The low-order bits of the hash determine the bucket, so they should
+// be well distributed.
+// If hashFunc is nil, NewLockFreeMapWithHash will panic.
+func NewLockFreeMapWithHash[K comparable, V any](hashFunc func(K) uint64) *LockFreeMap[K, V] {
+	if hashFunc == nil {
+		panic("hashFunc is nil")
+	}
+	m := &LockFreeMap[K, V]{hashFunc: hashFunc}
+	m.table.Store(newLockFreeTable[K, V](lockFreeInitialBuckets, true))
+	return m
+}
+
+// Clear removes all of the mappings from this map.
+func (m *LockFreeMap[K, V]) Clear() {
+	for {
+		table := m.table.Load()
+		if table.next.Load() != nil {
+			// complete the resizing in progress first
+			m.migrateAll(table)
+			continue
+		}
+		empty := newLockFreeTable[K, V](lockFreeInitialBuckets, true)
+		empty.cleared = true
+		if table.next.CompareAndSwap(nil, empty) {
+			m.migrateAll(table)
+			return
+		}
+	}
+}
+
+// CompareAndDelete removes the mapping for the specified key only if it
+// is currently mapped to oldValue (compared by pointer).
+// It returns true if the mapping was removed.
+func (m *LockFreeMap[K, V]) CompareAndDelete(key K, oldValue *V) bool {
+	var deleted bool
+	m.mutate(key, func(current *V, loaded bool) (*V, lockFreeOp) {
+		deleted = loaded && current == oldValue
+		if deleted {
+			return nil, lockFreeDelete
+		}
+		return nil, lockFreeKeep
+	})
+	return deleted
+}
+
+// CompareAndSwap replaces the value for the specified key with newValue
+// only if it is currently mapped to oldValue (compared by pointer).
+// It returns true if the value was replaced.
+func (m *LockFreeMap[K, V]) CompareAndSwap(key K, oldValue, newValue *V) bool {
+	var swapped bool
+	m.mutate(key, func(current *V, loaded bool) (*V, lockFreeOp) {
+		swapped = loaded && current == oldValue
+		if swapped {
+			return newValue, lockFreeStore
+		}
+		return nil, lockFreeKeep
+	})
+	return swapped
+}
+
+// Compute attempts to compute a mapping for the specified key and its
+// current value, or nil if there is no current mapping, and enters it
+// into the map. If the remapping function returns nil, the mapping is
+// removed. It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (m *LockFreeMap[K, V]) Compute(key K, remappingFunction func(K, *V) *V) *V {
+	var value *V
+	m.mutate(key, func(current *V, loaded bool) (*V, lockFreeOp) {
+		value = remappingFunction(key, current)
+		return value, lockFreeComputeOp(value, loaded)
+	})
+	return value
+}
+
+// ComputeIfAbsent attempts to compute a value using the given mapping
+// function and enters it into the map, if the specified key is not
+// already associated with a value.
+// Concurrent callers for the same absent key may each call the mapping
+// function, but only one of the computed values is stored and returned
+// to all of them.
+func (m *LockFreeMap[K, V]) ComputeIfAbsent(key K, mappingFunction func(K) *V) *V {
+	if value, ok := m.load(key); ok {
+		return value
+	}
+	computed := mappingFunction(key)
+	var value *V
+	m.mutate(key, func(current *V, loaded bool) (*V, lockFreeOp) {
+		if loaded {
+			value = current
+			return nil, lockFreeKeep
+		}
+		value = computed
+		return computed, lockFreeStore
+	})
+	return value
+}
+
+// ComputeIfPresent attempts to compute a new mapping given the key and
+// its current value, if the specified key is associated with a value.
+// If the remapping function returns nil, the mapping is removed.
+// It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (m *LockFreeMap[K, V]) ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	var value *V
+	m.mutate(key, func(current *V, loaded bool) (*V, lockFreeOp) {
+		if !loaded {
+			value = nil
+			return nil, lockFreeKeep
+		}
+		value = remappingFunction(key, current)
+		return value, lockFreeComputeOp(value, loaded)
+	})
+	return value
+}
+
+// ContainsKey returns true if this map contains a mapping for the
+// specified key.
+func (m *LockFreeMap[K, V]) ContainsKey(key K) bool {
+	_, ok := m.load(key)
+	return ok
+}
+
+// Get returns the value to which the specified key is mapped, or nil if
+// this map contains no mapping for the key.
+func (m *LockFreeMap[K, V]) Get(key K) *V {
+	value, _ := m.load(key)
+	return value
+}
+
+// GetOrDefault returns the value to which the specified key is mapped, or
+// defaultValue if this map contains no mapping for the key.
+func (m *LockFreeMap[K, V]) GetOrDefault(key K, defaultValue *V) *V {
+	if value, ok := m.load(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// IsEmpty returns true if this map contains no key-value mappings.
+func (m *LockFreeMap[K, V]) IsEmpty() bool {
+	return m.Size() == 0
+}
+
+// KeySet returns a slice of the keys contained in this map.
+func (m *LockFreeMap[K, V]) KeySet() []K {
+	keys := make([]K, 0, m.Size())
+	for key := range m.All() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Merge associates the specified key with the given value if it is not
+// already associated with a value. Otherwise, replaces the value with the
+// result of the remapping function applied to the current and the given
+// values, or removes the mapping if the result is nil.
+// It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (m *LockFreeMap[K, V]) Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	var merged *V
+	m.mutate(key, func(current *V, loaded bool) (*V, lockFreeOp) {
+		if !loaded {
+			merged = value
+			return value, lockFreeStore
+		}
+		merged = remappingFunction(current, value)
+		return merged, lockFreeComputeOp(merged, loaded)
+	})
+	return merged
+}
+
+// Put associates the specified value with the specified key in this map.
+func (m *LockFreeMap[K, V]) Put(key K, value *V) {
+	m.mutate(key, func(_ *V, _ bool) (*V, lockFreeOp) {
+		return value, lockFreeStore
+	})
+}
+
+// PutAll copies all of the key-value pairs from the given sequence
+// to this map.
+func (m *LockFreeMap[K, V]) PutAll(entries iter.Seq2[K, *V]) {
+	for key, value := range entries {
+		m.Put(key, value)
+	}
+}
+
+// PutIfAbsent associates the specified key with the given value if it is
+// not already associated with a value. It returns the current value,
+// or nil if the given value was stored.
+func (m *LockFreeMap[K, V]) PutIfAbsent(key K, value *V) *V {
+	current, loaded := m.mutate(key, func(_ *V, loaded bool) (*V, lockFreeOp) {
+		if loaded {
+			return nil, lockFreeKeep
+		}
+		return value, lockFreeStore
+	})
+	if loaded {
+		return current
+	}
+	return nil
+}
+
+// Remove removes the mapping for a key from this map if it is present,
+// returning the previous value or nil if none.
+func (m *LockFreeMap[K, V]) Remove(key K) *V {
+	previous, _ := m.mutate(key, func(_ *V, loaded bool) (*V, lockFreeOp) {
+		if loaded {
+			return nil, lockFreeDelete
+		}
+		return nil, lockFreeKeep
+	})
+	return previous
+}
+
+// RemoveAll removes the mappings for all of the given keys from this
+// map. It returns the number of removed mappings.
+func (m *LockFreeMap[K, V]) RemoveAll(keys iter.Seq[K]) int {
+	var removed int
+	for key := range keys {
+		_, loaded := m.mutate(key, func(_ *V, loaded bool) (*V, lockFreeOp) {
+			if loaded {
+				return nil, lockFreeDelete
+			}
+			return nil, lockFreeKeep
+		})
+		if loaded {
+			removed++
+		}
+	}
+	return removed
+}
+
+// RemoveIf removes all of the mappings that satisfy the given predicate.
+// It returns the number of removed mappings.
+// A mapping is not removed if its value changes after the predicate is
+// evaluated.
+func (m *LockFreeMap[K, V]) RemoveIf(predicate func(K, *V) bool) int {
+	var removed int
+	for key, value := range m.All() {
+		if predicate(key, value) && m.CompareAndDelete(key, value) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Replace replaces the value for the specified key only if it is
+// currently mapped to some value. It returns the previous value,
+// or nil if there was no mapping for the key.
+func (m *LockFreeMap[K, V]) Replace(key K, value *V) *V {
+	previous, _ := m.mutate(key, func(_ *V, loaded bool) (*V, lockFreeOp) {
+		if loaded {
+			return value, lockFreeStore
+		}
+		return nil, lockFreeKeep
+	})
+	return previous
+}
+
+// ReplaceAll replaces the value of each mapping with the result of the
+// given function applied to the mapping. If the function returns nil,
+// the mapping is removed.
+// The function may be called multiple times under contention.
+func (m *LockFreeMap[K, V]) ReplaceAll(function func(K, *V) *V) {
+	for key := range m.All() {
+		m.ComputeIfPresent(key, function)
+	}
+}
+
+// Size returns the number of key-value mappings in this map.
+func (m *LockFreeMap[K, V]) Size() int {
+	// the counter may be transiently negative while a removal
+	// overtakes the accounting of a concurrent insertion
+	size := m.size.Load()
+	if size > 0 {
+		return int(size)
+	}
+	return 0
+}
+
+// Snapshot returns an immutable copy of the mappings in this map.
+func (m *LockFreeMap[K, V]) Snapshot() *MapSnapshot[K, V] {
+	for {
+		table := m.table.Load()
+		if table.next.Load() != nil {
+			// complete the resizing in progress first
+			m.migrateAll(table)
+			continue
+		}
+		copied := newLockFreeTable[K, V](len(table.buckets), false)
+		copied.snapshot = true
+		if !table.next.CompareAndSwap(nil, copied) {
+			continue
+		}
+		m.freezeAll(table)
+		// migrated buckets retain their entries, and no bucket is
+		// modified once all of them are frozen
+		snapshot := newMapSnapshot[K, V](m.Size())
+		for i := range table.buckets {
+			for _, entry := range table.buckets[i].Load().entries {
+				snapshot.add(entry.key, entry.value)
+			}
+		}
+		m.migrateAll(table)
+		return snapshot
+	}
+}
+
+// Values returns a slice of the values contained in this map.
+func (m *LockFreeMap[K, V]) Values() []*V {
+	values := make([]*V, 0, m.Size())
+	for _, value := range m.All() {
+		values = append(values, value)
+	}
+	return values
+}
+
+// All returns an iterator of all key-value pairs in this map.
+// The order of the pairs is not specified.
+//
+// The iteration does not block other operations and is weakly consistent:
+// each key is yielded at most once, but mappings added or removed during
+// the iteration may or may not be reflected.
+func (m *LockFreeMap[K, V]) All() iter.Seq2[K, *V] {
+	return func(yield func(K, *V) bool) {
+		table := m.table.Load()
+		for i := range table.buckets {
+			if !m.yieldBucket(table, i, yield) {
+				return
+			}
+		}
+	}
+}
+
+// yieldBucket yields the entries of the bucket, following the bucket
+// to the next table if it has been migrated.
+func (m *LockFreeMap[K, V]) yieldBucket(table *lockFreeTable[K, V], i int,
+	yield func(K, *V) bool) bool {
+	bucket := table.buckets[i].Load()
+	if bucket.state != lockFreeMigrated {
+		for _, entry := range bucket.entries {
+			if !yield(entry.key, entry.value) {
+				return false
+			}
+		}
+		return true
+	}
+	next := table.next.Load()
+	switch {
+	case next.cleared:
+		// the mappings of the bucket were removed
+		return true
+	case next.snapshot:
+		return m.yieldBucket(next, i, yield)
+	}
+	return m.yieldBucket(next, i, yield) &&
+		m.yieldBucket(next, i+len(table.buckets), yield)
+}
+
+// load returns the value for the key and whether it was found.
+func (m *LockFreeMap[K, V]) load(key K) (*V, bool) {
+	hash := m.hashFunc(key)
+	table := m.table.Load()
+	for {
+		bucket := table.buckets[hash&table.mask].Load()
+		if bucket.state != lockFreeMigrated {
+			// frozen buckets are up to date until they are migrated
+			return bucket.find(hash, key)
+		}
+		table = table.next.Load()
+	}
+}
+
+// mutate applies the update function to the current value of the key and
+// atomically performs the returned operation. The update function may be
+// called multiple times under contention. It returns the value and whether
+// the key was present at the time of the successful update.
+func (m *LockFreeMap[K, V]) mutate(key K,
+	update func(current *V, loaded bool) (*V, lockFreeOp)) (*V, bool) {
+	hash := m.hashFunc(key)
+	root := m.table.Load()
+	table := root
+	for {
+		i := int(hash & table.mask)
+		bucket := table.buckets[i].Load()
+		switch bucket.state {
+		case lockFreeFrozen:
+			m.migrateBucket(table, i)
+			continue
+		case lockFreeMigrated:
+			table = table.next.Load()
+			continue
+		}
+
+		index := bucket.index(hash, key)
+		var current *V
+		loaded := index >= 0
+		if loaded {
+			current = bucket.entries[index].value
+		}
+		value, op := update(current, loaded)
+		if op == lockFreeKeep {
+			return current, loaded
+		}
+		if !table.buckets[i].CompareAndSwap(bucket, bucket.with(index, hash, key, value, op)) {
+			continue
+		}
+
+		switch {
+		case op == lockFreeStore && !loaded:
+			size := m.size.Add(1)
+			m.grow(root, size)
+		case op == lockFreeDelete:
+			m.size.Add(-1)
+		}
+		if root.next.Load() != nil {
+			m.helpMigrate(root)
+		}
+		return current, loaded
+	}
+}
+
+// grow starts the resizing of the table if the size exceeds the load factor.
+// If the resizing is in progress and the size exceeds the load factor of the
+// next table as well, grow completes the migration, so that the next table
+// can be resized in turn.
+func (m *LockFreeMap[K, V]) grow(table *lockFreeTable[K, V], size int64) {
+	if next := table.next.Load(); next != nil {
+		if size > int64(lockFreeLoadFactor*len(next.buckets)) {
+			m.migrateAll(table)
+		}
+		return
+	}
+	if size > int64(lockFreeLoadFactor*len(table.buckets)) {
+		table.next.CompareAndSwap(nil, newLockFreeTable[K, V](2*len(table.buckets), false))
+	}
+}
+
+// helpMigrate migrates the next unclaimed chunk of the table buckets.
+func (m *LockFreeMap[K, V]) helpMigrate(table *lockFreeTable[K, V]) {
+	end := int(table.claimed.Add(lockFreeMigrationChunk))
+	for i := max(0, end-lockFreeMigrationChunk); i < min(end, len(table.buckets)); i++ {
+		m.migrateBucket(table, i)
+	}
+}
+
+// migrateAll migrates all of the table buckets to the next table.
+func (m *LockFreeMap[K, V]) migrateAll(table *lockFreeTable[K, V]) {
+	for i := range table.buckets {
+		m.migrateBucket(table, i)
+	}
+}
+
+// migrateBucket freezes the bucket and moves its entries to the next table,
+// unless the next table is the result of Clear. The next table replaces the
+// current one when all of the buckets are migrated. The migration can be
+// performed by multiple goroutines concurrently.
+func (m *LockFreeMap[K, V]) migrateBucket(table *lockFreeTable[K, V], i int) {
+	next := table.next.Load()
+	if next.snapshot {
+		// the table is copied by Snapshot
+		m.freezeAll(table)
+	}
+	for {
+		bucket := m.freezeBucket(table, i)
+		if bucket.state == lockFreeMigrated {
+			return
+		}
+
+		switch {
+		case next.cleared:
+		case next.snapshot:
+			next.buckets[i].CompareAndSwap(nil, &lockFreeBucket[K, V]{entries: bucket.entries})
+		default:
+			low, high := bucket.split(table.mask + 1)
+			next.buckets[i].CompareAndSwap(nil, low)
+			next.buckets[i+len(table.buckets)].CompareAndSwap(nil, high)
+		}
+		migrated := &lockFreeBucket[K, V]{state: lockFreeMigrated, entries: bucket.entries}
+		if !table.buckets[i].CompareAndSwap(bucket, migrated) {
+			continue
+		}
+		if next.cleared {
+			m.size.Add(-int64(len(bucket.entries)))
+		}
+		if table.migrated.Add(1) == int64(len(table.buckets)) {
+			m.table.CompareAndSwap(table, next)
+		}
+		return
+	}
+}
+
+// freezeAll freezes all of the table buckets.
+func (m *LockFreeMap[K, V]) freezeAll(table *lockFreeTable[K, V]) {
+	if table.frozen.Load() {
+		return
+	}
+	for i := range table.buckets {
+		m.freezeBucket(table, i)
+	}
+	table.frozen.Store(true)
+}
+
+// freezeBucket freezes the bucket if it is not frozen or migrated yet,
+// and returns the resulting bucket.
+func (m *LockFreeMap[K, V]) freezeBucket(table *lockFreeTable[K, V], i int) *lockFreeBucket[K, V] {
+	for {
+		bucket := table.buckets[i].Load()
+		if bucket.state != lockFreeNormal {
+			return bucket
+		}
+		frozen := &lockFreeBucket[K, V]{state: lockFreeFrozen, entries: bucket.entries}
+		if table.buckets[i].CompareAndSwap(bucket, frozen) {
+			return frozen
+		}
+	}
+}
+
+// lockFreeComputeOp returns the operation to apply for a computed value.
+func lockFreeComputeOp[V any](value *V, loaded bool) lockFreeOp {
+	switch {
+	case value != nil:
+		return lockFreeStore
+	case loaded:
+		return lockFreeDelete
+	default:
+		return lockFreeKeep
+	}
+}
+
+// lockFreeOp is an update operation on a key.
+type lockFreeOp int
+
+const (
+	lockFreeKeep lockFreeOp = iota
+	lockFreeStore
+	lockFreeDelete
+)
+
+// lockFreeState is the migration state of a bucket.
+type lockFreeState int
+
+const (
+	lockFreeNormal lockFreeState = iota
+	lockFreeFrozen
+	lockFreeMigrated
+)
+
+type lockFreeTable[K comparable, V any] struct {
+	buckets  []atomic.Pointer[lockFreeBucket[K, V]]
+	mask     uint64
+	cleared  bool // replaces the previous table on Clear
+	snapshot bool // replaces the previous table copied by Snapshot
+	next     atomic.Pointer[lockFreeTable[K, V]]
+	claimed  atomic.Int64
+	migrated atomic.Int64
+	frozen   atomic.Bool // all of the buckets are frozen or migrated
+}
+
+// newLockFreeTable returns a new table with the given number of buckets,
+// which must be a power of two. If populate is false, the buckets are left
+// nil to be filled by the migration from the previous table.
+func newLockFreeTable[K comparable, V any](buckets int, populate bool) *lockFreeTable[K, V] {
+	table := &lockFreeTable[K, V]{
+		buckets: make([]atomic.Pointer[lockFreeBucket[K, V]], buckets),
+		mask:    uint64(buckets - 1),
+	}
+	if populate {
+		empty := &lockFreeBucket[K, V]{}
+		for i := range table.buckets {
+			table.buckets[i].Store(empty)
+		}
+	}
+	return table
+}
+
+// lockFreeBucket is an immutable bucket of the table. A migrated bucket
+// retains its last entries.
+type lockFreeBucket[K comparable, V any] struct {
+	state   lockFreeState
+	entries []lockFreeEntry[K, V]
+}
+
+type lockFreeEntry[K comparable, V any] struct {
+	hash  uint64
+	key   K
+	value *V
+}
+
+// index returns the index of the key entry, or -1 if not found.
+func (b *lockFreeBucket[K, V]) index(hash uint64, key K) int {
+	for i := range b.entries {
+		if b.entries[i].hash == hash && b.entries[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *lockFreeBucket[K, V]) find(hash uint64, key K) (*V, bool) {
+	if i := b.index(hash, key); i >= 0 {
+		return b.entries[i].value, true
+	}
+	return nil, false
+}
+
+// with returns a copy of the bucket with the operation applied to the
+// entry at the given index, where -1 denotes a new entry.
+func (b *lockFreeBucket[K, V]) with(index int, hash uint64, key K, value *V,
+	op lockFreeOp) *lockFreeBucket[K, V] {
+	var entries []lockFreeEntry[K, V]
+	switch {
+	case op == lockFreeDelete:
+		entries = make([]lockFreeEntry[K, V], 0, len(b.entries)-1)
+		entries = append(entries, b.entries[:index]...)
+		entries = append(entries, b.entries[index+1:]...)
+	case index < 0:
+		entries = make([]lockFreeEntry[K, V], len(b.entries), len(b.entries)+1)
+		copy(entries, b.entries)
+		entries = append(entries, lockFreeEntry[K, V]{hash, key, value})
+	default:
+		entries = make([]lockFreeEntry[K, V], len(b.entries))
+		copy(entries, b.entries)
+		entries[index].value = value
+	}
+	return &lockFreeBucket[K, V]{entries: entries}
+}
+
+// split divides the bucket entries into two buckets by the hash bit.
+func (b *lockFreeBucket[K, V]) split(bit uint64) (*lockFreeBucket[K, V], *lockFreeBucket[K, V]) {
+	low, high := &lockFreeBucket[K, V]{}, &lockFreeBucket[K, V]{}
+	for _, entry := range b.entries {
+		if entry.hash&bit == 0 {
+			low.entries = append(low.entries, entry)
+		} else {
+			high.entries = append(high.entries, entry)
+		}
+	}
+	return low, high
+}
@@ -0,0 +1,560 @@
+package async
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync"
+)
+
+// observableMapStripes is the number of locks to order the events by key.
+const observableMapStripes = 64
+
+// MapEventType represents the type of a MapEvent.
+type MapEventType int
+
+const (
+	// MapEventPut is emitted when a value is stored for a key.
+	MapEventPut MapEventType = iota
+	// MapEventRemove is emitted when the mapping for a key is removed.
+	MapEventRemove
+	// MapEventClear is emitted when all of the mappings are removed.
+	MapEventClear
+)
+
+// MapEvent represents a change of an ObservableMap.
+type MapEvent[K comparable, V any] struct {
+	// Type is the type of the change.
+	Type MapEventType
+	// Key is the changed key, or the zero value for MapEventClear.
+	Key K
+	// OldValue is the previous value, or nil if there was no mapping.
+	OldValue *V
+	// NewValue is the stored value, or nil if the mapping was removed.
+	NewValue *V
+}
+
+// ObservableMap wraps a Map and notifies subscribers of its changes.
+// An ObservableMap must not be copied.
+//
+// Changes of a key are serialized, so that the events for a key are emitted
+// in the order the changes were applied. The events are delivered to each
+// subscriber in order, by a dedicated goroutine, so the map operations are
+// never blocked by subscribers. As a consequence, the events for a slow
+// subscriber are queued in memory.
+//
+// The user functions are called without holding any lock, so they may access
+// the map. Their results are applied if the current value of the key has not
+// changed in the meantime, and otherwise they are called again. Therefore,
+// the bulk operations are applied entry by entry, each entry atomically.
+//
+// The events are emitted only for changes made through the ObservableMap,
+// so the wrapped map should not be modified directly.
+type ObservableMap[K comparable, V any] struct {
+	m        Map[K, V]
+	hashFunc func(K) uint64
+	stripes  [observableMapStripes]sync.Mutex
+
+	mtx         sync.RWMutex
+	subscribers map[*mapSubscriber[K, V]]struct{}
+}
+
+var _ Map[int, any] = (*ObservableMap[int, any])(nil)
+
+// NewObservableMap returns a new ObservableMap wrapping the given map.
+func NewObservableMap[K comparable, V any](m Map[K, V]) *ObservableMap[K, V] {
+	return &ObservableMap[K, V]{
+		m:           m,
+		hashFunc:    newHashFunc[K](),
+		subscribers: make(map[*mapSubscriber[K, V]]struct{}),
+	}
+}
+
+// Subscribe registers the handler to be called for each subsequent change
+// of the map. The handler is called sequentially, in the order of events.
+// It returns a function to unsubscribe the handler, which stops the delivery
+// of events.
+func (ob *ObservableMap[K, V]) Subscribe(handler func(MapEvent[K, V])) (unsubscribe func()) {
+	subscriber := ob.subscribe(nil, handler)
+	return func() { ob.unsubscribe(subscriber) }
+}
+
+// Events returns a channel of the subsequent changes of the map. The
+// subscription is canceled, and the channel is closed, when the context
+// is done.
+func (ob *ObservableMap[K, V]) Events(ctx context.Context) <-chan MapEvent[K, V] {
+	return ob.channel(ctx, nil)
+}
+
+// Watch returns a channel of the subsequent changes of the specified key,
+// including the clearing of the map. The subscription is canceled, and the
+// channel is closed, when the context is done.
+func (ob *ObservableMap[K, V]) Watch(ctx context.Context, key K) <-chan MapEvent[K, V] {
+	return ob.channel(ctx, func(event MapEvent[K, V]) bool {
+		return event.Type == MapEventClear || event.Key == key
+	})
+}
+
+// Clear removes all of the mappings from this map.
+func (ob *ObservableMap[K, V]) Clear() {
+	ob.lockAll()
+	defer ob.unlockAll()
+	ob.m.Clear()
+	ob.emit(MapEvent[K, V]{Type: MapEventClear})
+}
+
+// CompareAndDelete removes the mapping for the specified key only if it
+// is currently mapped to oldValue (compared by pointer).
+// It returns true if the mapping was removed.
+func (ob *ObservableMap[K, V]) CompareAndDelete(key K, oldValue *V) bool {
+	defer ob.lock(key)()
+	if !ob.m.CompareAndDelete(key, oldValue) {
+		return false
+	}
+	ob.emitRemove(key, oldValue)
+	return true
+}
+
+// CompareAndSwap replaces the value for the specified key with newValue
+// only if it is currently mapped to oldValue (compared by pointer).
+// It returns true if the value was replaced.
+func (ob *ObservableMap[K, V]) CompareAndSwap(key K, oldValue, newValue *V) bool {
+	defer ob.lock(key)()
+	if !ob.m.CompareAndSwap(key, oldValue, newValue) {
+		return false
+	}
+	ob.emitPut(key, oldValue, newValue)
+	return true
+}
+
+// Compute attempts to compute a mapping for the specified key and its
+// current value, or nil if there is no current mapping, and enters it
+// into the map. If the remapping function returns nil, the mapping is
+// removed. It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (ob *ObservableMap[K, V]) Compute(key K, remappingFunction func(K, *V) *V) *V {
+	for {
+		current := ob.m.Get(key)
+		value := remappingFunction(key, current)
+		if ob.update(key, current, value) {
+			return value
+		}
+	}
+}
+
+// ComputeIfAbsent attempts to compute a value using the given mapping
+// function and enters it into the map, if the specified key is not
+// already associated with a value.
+// Concurrent callers for the same absent key may each call the mapping
+// function, but only one of the computed values is stored and returned
+// to all of them.
+func (ob *ObservableMap[K, V]) ComputeIfAbsent(key K, mappingFunction func(K) *V) *V {
+	if value := ob.m.Get(key); value != nil {
+		return value
+	}
+	computed := mappingFunction(key)
+	defer ob.lock(key)()
+	if current := ob.m.PutIfAbsent(key, computed); current != nil {
+		return current
+	}
+	ob.emitPut(key, nil, computed)
+	return computed
+}
+
+// ComputeIfPresent attempts to compute a new mapping given the key and
+// its current value, if the specified key is associated with a value.
+// If the remapping function returns nil, the mapping is removed.
+// It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (ob *ObservableMap[K, V]) ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	for {
+		current := ob.m.Get(key)
+		if current == nil {
+			return nil
+		}
+		value := remappingFunction(key, current)
+		if ob.update(key, current, value) {
+			return value
+		}
+	}
+}
+
+// ContainsKey returns true if this map contains a mapping for the
+// specified key.
+func (ob *ObservableMap[K, V]) ContainsKey(key K) bool {
+	return ob.m.ContainsKey(key)
+}
+
+// Get returns the value to which the specified key is mapped, or nil if
+// this map contains no mapping for the key.
+func (ob *ObservableMap[K, V]) Get(key K) *V {
+	return ob.m.Get(key)
+}
+
+// GetOrDefault returns the value to which the specified key is mapped, or
+// defaultValue if this map contains no mapping for the key.
+func (ob *ObservableMap[K, V]) GetOrDefault(key K, defaultValue *V) *V {
+	return ob.m.GetOrDefault(key, defaultValue)
+}
+
+// IsEmpty returns true if this map contains no key-value mappings.
+func (ob *ObservableMap[K, V]) IsEmpty() bool {
+	return ob.m.IsEmpty()
+}
+
+// KeySet returns a slice of the keys contained in this map.
+func (ob *ObservableMap[K, V]) KeySet() []K {
+	return ob.m.KeySet()
+}
+
+// Merge associates the specified key with the given value if it is not
+// already associated with a value. Otherwise, replaces the value with the
+// result of the remapping function applied to the current and the given
+// values, or removes the mapping if the result is nil.
+// It returns the new value or nil if none.
+// The remapping function may be called multiple times under contention.
+func (ob *ObservableMap[K, V]) Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	for {
+		current := ob.m.Get(key)
+		merged := value
+		if current != nil {
+			merged = remappingFunction(current, value)
+		}
+		if ob.update(key, current, merged) {
+			return merged
+		}
+	}
+}
+
+// Put associates the specified value with the specified key in this map.
+func (ob *ObservableMap[K, V]) Put(key K, value *V) {
+	defer ob.lock(key)()
+	current := ob.m.Get(key)
+	ob.m.Put(key, value)
+	ob.emitPut(key, current, value)
+}
+
+// PutAll copies all of the key-value pairs from the given sequence
+// to this map.
+func (ob *ObservableMap[K, V]) PutAll(entries iter.Seq2[K, *V]) {
+	collected := collectEntries(entries)
+	keys := make([]K, len(collected))
+	for i, entry := range collected {
+		keys[i] = entry.key
+	}
+	defer ob.lockKeys(keys)()
+	events := make([]MapEvent[K, V], len(collected))
+	latest := make(map[K]*V, len(collected))
+	for i, entry := range collected {
+		current, ok := latest[entry.key]
+		if !ok {
+			current = ob.m.Get(entry.key)
+		}
+		latest[entry.key] = entry.value
+		events[i] = MapEvent[K, V]{Type: MapEventPut, Key: entry.key, OldValue: current, NewValue: entry.value}
+	}
+	ob.m.PutAll(func(yield func(K, *V) bool) {
+		for _, entry := range collected {
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	})
+	ob.emit(events...)
+}
+
+// PutIfAbsent associates the specified key with the given value if it is
+// not already associated with a value. It returns the current value,
+// or nil if the given value was stored.
+func (ob *ObservableMap[K, V]) PutIfAbsent(key K, value *V) *V {
+	defer ob.lock(key)()
+	present := ob.m.ContainsKey(key)
+	current := ob.m.PutIfAbsent(key, value)
+	if !present {
+		ob.emitPut(key, nil, value)
+	}
+	return current
+}
+
+// Remove removes the mapping for a key from this map if it is present,
+// returning the previous value or nil if none.
+func (ob *ObservableMap[K, V]) Remove(key K) *V {
+	defer ob.lock(key)()
+	present := ob.m.ContainsKey(key)
+	value := ob.m.Remove(key)
+	if present {
+		ob.emitRemove(key, value)
+	}
+	return value
+}
+
+// RemoveAll removes the mappings for all of the given keys from this
+// map. It returns the number of removed mappings.
+func (ob *ObservableMap[K, V]) RemoveAll(keys iter.Seq[K]) int {
+	collected := slices.Collect(keys)
+	defer ob.lockKeys(collected)()
+	snapshot := newMapSnapshot[K, V](len(collected))
+	for _, key := range collected {
+		if ob.m.ContainsKey(key) {
+			snapshot.add(key, ob.m.Get(key))
+		}
+	}
+	removed := ob.m.RemoveAll(slices.Values(collected))
+	for key, value := range snapshot.All() {
+		ob.emitRemove(key, value)
+	}
+	return removed
+}
+
+// RemoveIf removes all of the mappings that satisfy the given predicate.
+// It returns the number of removed mappings.
+// A mapping is not removed if its value changes after the predicate is
+// evaluated.
+func (ob *ObservableMap[K, V]) RemoveIf(predicate func(K, *V) bool) int {
+	var removed int
+	for key, value := range ob.m.Snapshot().All() {
+		if predicate(key, value) && ob.CompareAndDelete(key, value) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Replace replaces the value for the specified key only if it is
+// currently mapped to some value. It returns the previous value,
+// or nil if there was no mapping for the key.
+func (ob *ObservableMap[K, V]) Replace(key K, value *V) *V {
+	defer ob.lock(key)()
+	present := ob.m.ContainsKey(key)
+	previous := ob.m.Replace(key, value)
+	if present {
+		ob.emitPut(key, previous, value)
+	}
+	return previous
+}
+
+// ReplaceAll replaces the value of each mapping with the result of the
+// given function applied to the mapping. If the function returns nil,
+// the mapping is removed.
+// The function may be called multiple times under contention.
+func (ob *ObservableMap[K, V]) ReplaceAll(function func(K, *V) *V) {
+	for _, key := range ob.m.KeySet() {
+		ob.ComputeIfPresent(key, function)
+	}
+}
+
+// Size returns the number of key-value mappings in this map.
+func (ob *ObservableMap[K, V]) Size() int {
+	return ob.m.Size()
+}
+
+// Snapshot returns an immutable copy of the mappings in this map.
+func (ob *ObservableMap[K, V]) Snapshot() *MapSnapshot[K, V] {
+	return ob.m.Snapshot()
+}
+
+// Values returns a slice of the values contained in this map.
+func (ob *ObservableMap[K, V]) Values() []*V {
+	return ob.m.Values()
+}
+
+// All returns an iterator of all key-value pairs in this map.
+// The order of the pairs is not specified.
+func (ob *ObservableMap[K, V]) All() iter.Seq2[K, *V] {
+	return ob.m.All()
+}
+
+// lock locks the stripe of the key and returns the function to unlock it.
+func (ob *ObservableMap[K, V]) lock(key K) func() {
+	stripe := &ob.stripes[ob.hashFunc(key)%observableMapStripes]
+	stripe.Lock()
+	return stripe.Unlock
+}
+
+// lockKeys locks the stripes of the keys in ascending order and returns
+// the function to unlock them.
+func (ob *ObservableMap[K, V]) lockKeys(keys []K) func() {
+	indices := make([]uint64, len(keys))
+	for i, key := range keys {
+		indices[i] = ob.hashFunc(key) % observableMapStripes
+	}
+	slices.Sort(indices)
+	indices = slices.Compact(indices)
+	for _, i := range indices {
+		ob.stripes[i].Lock()
+	}
+	return func() {
+		for _, i := range indices {
+			ob.stripes[i].Unlock()
+		}
+	}
+}
+
+func (ob *ObservableMap[K, V]) lockAll() {
+	for i := range ob.stripes {
+		ob.stripes[i].Lock()
+	}
+}
+
+func (ob *ObservableMap[K, V]) unlockAll() {
+	for i := range ob.stripes {
+		ob.stripes[i].Unlock()
+	}
+}
+
+func (ob *ObservableMap[K, V]) emitPut(key K, oldValue, newValue *V) {
+	ob.emit(MapEvent[K, V]{Type: MapEventPut, Key: key, OldValue: oldValue, NewValue: newValue})
+}
+
+func (ob *ObservableMap[K, V]) emitRemove(key K, oldValue *V) {
+	ob.emit(MapEvent[K, V]{Type: MapEventRemove, Key: key, OldValue: oldValue})
+}
+
+// update replaces the current value of the key with the given value, or
+// removes the mapping if the value is nil, and emits the event. It returns
+// false if the current value was changed concurrently.
+func (ob *ObservableMap[K, V]) update(key K, current, value *V) bool {
+	defer ob.lock(key)()
+	switch {
+	case current == nil && value == nil:
+		return ob.m.Get(key) == nil
+	case current == nil:
+		if ob.m.PutIfAbsent(key, value) != nil {
+			return false
+		}
+	case value == nil:
+		if !ob.m.CompareAndDelete(key, current) {
+			return false
+		}
+	default:
+		if !ob.m.CompareAndSwap(key, current, value) {
+			return false
+		}
+	}
+	ob.emitComputed(key, current, current != nil, value)
+	return true
+}
+
+// emitComputed emits the event for the result of a remapping function.
+func (ob *ObservableMap[K, V]) emitComputed(key K, oldValue *V, present bool, newValue *V) {
+	switch {
+	case newValue != nil:
+		ob.emitPut(key, oldValue, newValue)
+	case present:
+		ob.emitRemove(key, oldValue)
+	}
+}
+
+// emit queues the events for delivery to the subscribers.
+func (ob *ObservableMap[K, V]) emit(events ...MapEvent[K, V]) {
+	if len(events) == 0 {
+		return
+	}
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+	for subscriber := range ob.subscribers {
+		subscriber.enqueue(events)
+	}
+}
+
+// channel returns a channel of the events satisfying the filter.
+func (ob *ObservableMap[K, V]) channel(ctx context.Context,
+	filter func(MapEvent[K, V]) bool) <-chan MapEvent[K, V] {
+	ch := make(chan MapEvent[K, V])
+	subscriber := newMapSubscriber(filter)
+	subscriber.handler = func(event MapEvent[K, V]) {
+		select {
+		case ch <- event:
+		case <-subscriber.done:
+		}
+	}
+	subscriber.onStop = func() { close(ch) }
+	ob.register(subscriber)
+	context.AfterFunc(ctx, func() { ob.unsubscribe(subscriber) })
+	return ch
+}
+
+func (ob *ObservableMap[K, V]) subscribe(filter func(MapEvent[K, V]) bool,
+	handler func(MapEvent[K, V])) *mapSubscriber[K, V] {
+	subscriber := newMapSubscriber(filter)
+	subscriber.handler = handler
+	ob.register(subscriber)
+	return subscriber
+}
+
+// register adds the subscriber and starts the delivery of events.
+func (ob *ObservableMap[K, V]) register(subscriber *mapSubscriber[K, V]) {
+	ob.mtx.Lock()
+	ob.subscribers[subscriber] = struct{}{}
+	ob.mtx.Unlock()
+	go subscriber.run()
+}
+
+func (ob *ObservableMap[K, V]) unsubscribe(subscriber *mapSubscriber[K, V]) {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	if _, ok := ob.subscribers[subscriber]; ok {
+		delete(ob.subscribers, subscriber)
+		close(subscriber.done)
+	}
+}
+
+// mapSubscriber queues the events and delivers them to the handler
+// in a dedicated goroutine, which exits when the subscriber is done.
+type mapSubscriber[K comparable, V any] struct {
+	filter  func(MapEvent[K, V]) bool
+	handler func(MapEvent[K, V])
+	onStop  func()
+
+	mtx    sync.Mutex
+	queue  []MapEvent[K, V]
+	signal chan struct{}
+	done   chan struct{}
+}
+
+func newMapSubscriber[K comparable, V any](filter func(MapEvent[K, V]) bool) *mapSubscriber[K, V] {
+	return &mapSubscriber[K, V]{
+		filter: filter,
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *mapSubscriber[K, V]) enqueue(events []MapEvent[K, V]) {
+	s.mtx.Lock()
+	for _, event := range events {
+		if s.filter == nil || s.filter(event) {
+			s.queue = append(s.queue, event)
+		}
+	}
+	s.mtx.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *mapSubscriber[K, V]) run() {
+	if s.onStop != nil {
+		defer s.onStop()
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.signal:
+		}
+		s.mtx.Lock()
+		events := s.queue
+		s.queue = nil
+		s.mtx.Unlock()
+		for _, event := range events {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.handler(event)
+			}
+		}
+	}
+}
@@ -0,0 +1,575 @@
+package async
+
+import (
+	"cmp"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// orderedMapMaxLevel is the maximum number of levels in the skip list.
+const orderedMapMaxLevel = 32
+
+// OrderedMap implements the async.Map interface in a thread-safe manner,
+// keeping the mappings sorted by key in a skip list guarded by a sync.RWMutex.
+// In addition to the Map operations, it supports navigation and range queries.
+// An OrderedMap must not be copied.
+//
+// The iterators returned by All, Range and Descending do not hold the lock
+// while yielding, so the map can be modified during the iteration. They are
+// weakly consistent: each key is yielded at most once, in order, but
+// concurrent modifications may or may not be reflected. The bulk operations
+// are atomic, and Snapshot is consistent, as they are performed under the
+// lock.
+type OrderedMap[K cmp.Ordered, V any] struct {
+	mtx   sync.RWMutex
+	head  *orderedMapNode[K, V]
+	tail  *orderedMapNode[K, V]
+	level int
+	size  int
+}
+
+var _ Map[int, any] = (*OrderedMap[int, any])(nil)
+
+type orderedMapNode[K cmp.Ordered, V any] struct {
+	key     K
+	value   *V
+	next    []*orderedMapNode[K, V]
+	prev    *orderedMapNode[K, V] // nil for the first node
+	deleted bool
+}
+
+// NewOrderedMap returns a new OrderedMap.
+func NewOrderedMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		head:  &orderedMapNode[K, V]{next: make([]*orderedMapNode[K, V], orderedMapMaxLevel)},
+		level: 1,
+	}
+}
+
+// Clear removes all of the mappings from this map.
+func (om *OrderedMap[K, V]) Clear() {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	for node := om.head.next[0]; node != nil; node = node.next[0] {
+		node.deleted = true
+	}
+	clear(om.head.next)
+	om.tail = nil
+	om.level = 1
+	om.size = 0
+}
+
+// CompareAndDelete removes the mapping for the specified key only if it
+// is currently mapped to oldValue (compared by pointer).
+// It returns true if the mapping was removed.
+func (om *OrderedMap[K, V]) CompareAndDelete(key K, oldValue *V) bool {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	node := om.find(key)
+	if node == nil || node.value != oldValue {
+		return false
+	}
+	om.delete(key)
+	return true
+}
+
+// CompareAndSwap replaces the value for the specified key with newValue
+// only if it is currently mapped to oldValue (compared by pointer).
+// It returns true if the value was replaced.
+func (om *OrderedMap[K, V]) CompareAndSwap(key K, oldValue, newValue *V) bool {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	node := om.find(key)
+	if node == nil || node.value != oldValue {
+		return false
+	}
+	node.value = newValue
+	return true
+}
+
+// Compute attempts to compute a mapping for the specified key and its
+// current value, or nil if there is no current mapping, and enters it
+// into the map. If the remapping function returns nil, the mapping is
+// removed. It returns the new value or nil if none.
+func (om *OrderedMap[K, V]) Compute(key K, remappingFunction func(K, *V) *V) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	var current *V
+	node := om.find(key)
+	if node != nil {
+		current = node.value
+	}
+	value := remappingFunction(key, current)
+	switch {
+	case value == nil:
+		om.delete(key)
+	case node != nil:
+		node.value = value
+	default:
+		om.insert(key, value)
+	}
+	return value
+}
+
+// ComputeIfAbsent attempts to compute a value using the given mapping
+// function and enters it into the map, if the specified key is not
+// already associated with a value.
+func (om *OrderedMap[K, V]) ComputeIfAbsent(key K, mappingFunction func(K) *V) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	if node := om.find(key); node != nil {
+		return node.value
+	}
+	value := mappingFunction(key)
+	om.insert(key, value)
+	return value
+}
+
+// ComputeIfPresent attempts to compute a new mapping given the key and
+// its current value, if the specified key is associated with a value.
+// If the remapping function returns nil, the mapping is removed.
+// It returns the new value or nil if none.
+func (om *OrderedMap[K, V]) ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	node := om.find(key)
+	if node == nil {
+		return nil
+	}
+	value := remappingFunction(key, node.value)
+	if value == nil {
+		om.delete(key)
+	} else {
+		node.value = value
+	}
+	return value
+}
+
+// ContainsKey returns true if this map contains a mapping for the
+// specified key.
+func (om *OrderedMap[K, V]) ContainsKey(key K) bool {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	return om.find(key) != nil
+}
+
+// Get returns the value to which the specified key is mapped, or nil if
+// this map contains no mapping for the key.
+func (om *OrderedMap[K, V]) Get(key K) *V {
+	return om.GetOrDefault(key, nil)
+}
+
+// GetOrDefault returns the value to which the specified key is mapped, or
+// defaultValue if this map contains no mapping for the key.
+func (om *OrderedMap[K, V]) GetOrDefault(key K, defaultValue *V) *V {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	if node := om.find(key); node != nil {
+		return node.value
+	}
+	return defaultValue
+}
+
+// IsEmpty returns true if this map contains no key-value mappings.
+func (om *OrderedMap[K, V]) IsEmpty() bool {
+	return om.Size() == 0
+}
+
+// KeySet returns a slice of the keys contained in this map,
+// in ascending order.
+func (om *OrderedMap[K, V]) KeySet() []K {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	keys := make([]K, 0, om.size)
+	for node := om.head.next[0]; node != nil; node = node.next[0] {
+		keys = append(keys, node.key)
+	}
+	return keys
+}
+
+// Merge associates the specified key with the given value if it is not
+// already associated with a value. Otherwise, replaces the value with the
+// result of the remapping function applied to the current and the given
+// values, or removes the mapping if the result is nil.
+// It returns the new value or nil if none.
+func (om *OrderedMap[K, V]) Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	node := om.find(key)
+	if node == nil {
+		om.insert(key, value)
+		return value
+	}
+	value = remappingFunction(node.value, value)
+	if value == nil {
+		om.delete(key)
+	} else {
+		node.value = value
+	}
+	return value
+}
+
+// Put associates the specified value with the specified key in this map.
+func (om *OrderedMap[K, V]) Put(key K, value *V) {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	if node := om.find(key); node != nil {
+		node.value = value
+	} else {
+		om.insert(key, value)
+	}
+}
+
+// PutAll copies all of the key-value pairs from the given sequence
+// to this map.
+func (om *OrderedMap[K, V]) PutAll(entries iter.Seq2[K, *V]) {
+	collected := collectEntries(entries)
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	for _, entry := range collected {
+		if node := om.find(entry.key); node != nil {
+			node.value = entry.value
+		} else {
+			om.insert(entry.key, entry.value)
+		}
+	}
+}
+
+// PutIfAbsent associates the specified key with the given value if it is
+// not already associated with a value. It returns the current value,
+// or nil if the given value was stored.
+func (om *OrderedMap[K, V]) PutIfAbsent(key K, value *V) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	if node := om.find(key); node != nil {
+		return node.value
+	}
+	om.insert(key, value)
+	return nil
+}
+
+// Remove removes the mapping for a key from this map if it is present,
+// returning the previous value or nil if none.
+func (om *OrderedMap[K, V]) Remove(key K) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	if node := om.delete(key); node != nil {
+		return node.value
+	}
+	return nil
+}
+
+// RemoveAll removes the mappings for all of the given keys from this
+// map. It returns the number of removed mappings.
+func (om *OrderedMap[K, V]) RemoveAll(keys iter.Seq[K]) int {
+	collected := slices.Collect(keys)
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	var removed int
+	for _, key := range collected {
+		if om.delete(key) != nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// RemoveIf removes all of the mappings that satisfy the given predicate.
+// It returns the number of removed mappings.
+func (om *OrderedMap[K, V]) RemoveIf(predicate func(K, *V) bool) int {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	var removed int
+	for node := om.head.next[0]; node != nil; node = node.next[0] {
+		if predicate(node.key, node.value) {
+			om.delete(node.key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Replace replaces the value for the specified key only if it is
+// currently mapped to some value. It returns the previous value,
+// or nil if there was no mapping for the key.
+func (om *OrderedMap[K, V]) Replace(key K, value *V) *V {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	node := om.find(key)
+	if node == nil {
+		return nil
+	}
+	previous := node.value
+	node.value = value
+	return previous
+}
+
+// ReplaceAll replaces the value of each mapping with the result of the
+// given function applied to the mapping. If the function returns nil,
+// the mapping is removed.
+func (om *OrderedMap[K, V]) ReplaceAll(function func(K, *V) *V) {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+	for node := om.head.next[0]; node != nil; node = node.next[0] {
+		if value := function(node.key, node.value); value == nil {
+			om.delete(node.key)
+		} else {
+			node.value = value
+		}
+	}
+}
+
+// Size returns the number of key-value mappings in this map.
+func (om *OrderedMap[K, V]) Size() int {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	return om.size
+}
+
+// Snapshot returns an immutable copy of the mappings in this map,
+// which preserves the ascending order of keys.
+func (om *OrderedMap[K, V]) Snapshot() *MapSnapshot[K, V] {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	snapshot := newMapSnapshot[K, V](om.size)
+	for node := om.head.next[0]; node != nil; node = node.next[0] {
+		snapshot.add(node.key, node.value)
+	}
+	return snapshot
+}
+
+// Values returns a slice of the values contained in this map,
+// in ascending order of their keys.
+func (om *OrderedMap[K, V]) Values() []*V {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	values := make([]*V, 0, om.size)
+	for node := om.head.next[0]; node != nil; node = node.next[0] {
+		values = append(values, node.value)
+	}
+	return values
+}
+
+// All returns an iterator of all key-value pairs in this map,
+// in ascending order of keys.
+func (om *OrderedMap[K, V]) All() iter.Seq2[K, *V] {
+	return om.seq(func() *orderedMapNode[K, V] {
+		return om.head.next[0]
+	}, true, func(K) bool { return true })
+}
+
+// Descending returns an iterator of all key-value pairs in this map,
+// in descending order of keys.
+func (om *OrderedMap[K, V]) Descending() iter.Seq2[K, *V] {
+	return om.seq(func() *orderedMapNode[K, V] {
+		return om.tail
+	}, false, func(K) bool { return true })
+}
+
+// Range returns an iterator of the key-value pairs in this map with keys
+// greater than or equal to from and less than to, in ascending order.
+func (om *OrderedMap[K, V]) Range(from, to K) iter.Seq2[K, *V] {
+	return om.seq(func() *orderedMapNode[K, V] {
+		return om.ceiling(from, true)
+	}, true, func(key K) bool { return cmp.Less(key, to) })
+}
+
+// First returns the mapping with the least key in this map.
+// The returned boolean is false if the map is empty.
+func (om *OrderedMap[K, V]) First() (K, *V, bool) {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	return om.head.next[0].entry()
+}
+
+// Last returns the mapping with the greatest key in this map.
+// The returned boolean is false if the map is empty.
+func (om *OrderedMap[K, V]) Last() (K, *V, bool) {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	return om.tail.entry()
+}
+
+// Floor returns the mapping with the greatest key less than or equal to
+// the given key. The returned boolean is false if there is no such key.
+func (om *OrderedMap[K, V]) Floor(key K) (K, *V, bool) {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	return om.floor(key, true).entry()
+}
+
+// Ceiling returns the mapping with the least key greater than or equal to
+// the given key. The returned boolean is false if there is no such key.
+func (om *OrderedMap[K, V]) Ceiling(key K) (K, *V, bool) {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+	return om.ceiling(key, true).entry()
+}
+
+// seq returns an iterator starting from the node returned by first and
+// proceeding in the given direction while the keys satisfy the condition.
+// The read lock is released while yielding.
+func (om *OrderedMap[K, V]) seq(first func() *orderedMapNode[K, V], ascending bool,
+	cond func(K) bool) iter.Seq2[K, *V] {
+	return func(yield func(K, *V) bool) {
+		om.mtx.RLock()
+		node := first()
+		for node != nil && cond(node.key) {
+			key, value := node.key, node.value
+			om.mtx.RUnlock()
+			if !yield(key, value) {
+				return
+			}
+			om.mtx.RLock()
+			node = om.successor(node, key, ascending)
+		}
+		om.mtx.RUnlock()
+	}
+}
+
+// successor returns the node following the given node in the given
+// direction. If the node has been removed since it was yielded, the
+// successor is looked up by key.
+func (om *OrderedMap[K, V]) successor(node *orderedMapNode[K, V], key K,
+	ascending bool) *orderedMapNode[K, V] {
+	switch {
+	case !node.deleted && ascending:
+		return node.next[0]
+	case !node.deleted:
+		return node.prev
+	case ascending:
+		return om.ceiling(key, false)
+	default:
+		return om.floor(key, false)
+	}
+}
+
+// find returns the node for the key, or nil if not found.
+func (om *OrderedMap[K, V]) find(key K) *orderedMapNode[K, V] {
+	node := om.ceiling(key, true)
+	if node != nil && cmp.Compare(node.key, key) == 0 {
+		return node
+	}
+	return nil
+}
+
+// ceiling returns the node with the least key greater than the given key,
+// or equal to it if inclusive, or nil if there is no such node.
+func (om *OrderedMap[K, V]) ceiling(key K, inclusive bool) *orderedMapNode[K, V] {
+	node := om.head
+	for level := om.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && om.before(next.key, key, !inclusive); next = node.next[level] {
+			node = next
+		}
+	}
+	return node.next[0]
+}
+
+// floor returns the node with the greatest key less than the given key,
+// or equal to it if inclusive, or nil if there is no such node.
+func (om *OrderedMap[K, V]) floor(key K, inclusive bool) *orderedMapNode[K, V] {
+	node := om.head
+	for level := om.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && om.before(next.key, key, inclusive); next = node.next[level] {
+			node = next
+		}
+	}
+	if node == om.head {
+		return nil
+	}
+	return node
+}
+
+// before returns true if a is less than b, or equal to it if orEqual.
+func (om *OrderedMap[K, V]) before(a, b K, orEqual bool) bool {
+	c := cmp.Compare(a, b)
+	return c < 0 || (orEqual && c == 0)
+}
+
+// insert adds a new node for the key, which must not be present.
+func (om *OrderedMap[K, V]) insert(key K, value *V) {
+	var update [orderedMapMaxLevel]*orderedMapNode[K, V]
+	node := om.head
+	for level := om.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && cmp.Less(next.key, key); next = node.next[level] {
+			node = next
+		}
+		update[level] = node
+	}
+
+	level := randomOrderedMapLevel()
+	for ; om.level < level; om.level++ {
+		update[om.level] = om.head
+	}
+	inserted := &orderedMapNode[K, V]{
+		key:   key,
+		value: value,
+		next:  make([]*orderedMapNode[K, V], level),
+	}
+	for i := 0; i < level; i++ {
+		inserted.next[i] = update[i].next[i]
+		update[i].next[i] = inserted
+	}
+
+	if update[0] != om.head {
+		inserted.prev = update[0]
+	}
+	if next := inserted.next[0]; next != nil {
+		next.prev = inserted
+	} else {
+		om.tail = inserted
+	}
+	om.size++
+}
+
+// delete removes the node for the key and returns it,
+// or nil if the key is not present.
+func (om *OrderedMap[K, V]) delete(key K) *orderedMapNode[K, V] {
+	var update [orderedMapMaxLevel]*orderedMapNode[K, V]
+	node := om.head
+	for level := om.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && cmp.Less(next.key, key); next = node.next[level] {
+			node = next
+		}
+		update[level] = node
+	}
+
+	deleted := node.next[0]
+	if deleted == nil || cmp.Compare(deleted.key, key) != 0 {
+		return nil
+	}
+	for i := range deleted.next {
+		update[i].next[i] = deleted.next[i]
+	}
+	for om.level > 1 && om.head.next[om.level-1] == nil {
+		om.level--
+	}
+
+	if next := deleted.next[0]; next != nil {
+		next.prev = deleted.prev
+	} else {
+		om.tail = deleted.prev
+	}
+	deleted.deleted = true
+	om.size--
+	return deleted
+}
+
+// entry returns the key and value of the node, if not nil.
+func (node *orderedMapNode[K, V]) entry() (K, *V, bool) {
+	if node == nil {
+		var zero K
+		return zero, nil, false
+	}
+	return node.key, node.value, true
+}
+
+// randomOrderedMapLevel returns a random level for a new node, where each
+// level is reached with the probability of 1/4.
+func randomOrderedMapLevel() int {
+	level := 1 + bits.TrailingZeros64(rand.Uint64())/2
+	return min(level, orderedMapMaxLevel)
+}
@@ -0,0 +1,131 @@
+package async
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// MapEncoder writes encoded values to an underlying stream.
+// It is implemented by *gob.Encoder and *json.Encoder.
+type MapEncoder interface {
+	Encode(v any) error
+}
+
+// MapDecoder reads encoded values from an underlying stream, returning
+// io.EOF when the stream is exhausted.
+// It is implemented by *gob.Decoder and *json.Decoder.
+type MapDecoder interface {
+	Decode(v any) error
+}
+
+// MapCodec creates the encoders and decoders used by SaveMap and LoadMap.
+// Any serialization format can be plugged in by providing the constructors
+// of a streaming encoder and decoder.
+type MapCodec struct {
+	NewEncoder func(io.Writer) MapEncoder
+	NewDecoder func(io.Reader) MapDecoder
+}
+
+var (
+	// GobCodec serializes the map entries using encoding/gob.
+	GobCodec = MapCodec{
+		NewEncoder: func(w io.Writer) MapEncoder { return gob.NewEncoder(w) },
+		NewDecoder: func(r io.Reader) MapDecoder { return gob.NewDecoder(r) },
+	}
+	// JSONCodec serializes the map entries as a stream of JSON objects
+	// using encoding/json.
+	JSONCodec = MapCodec{
+		NewEncoder: func(w io.Writer) MapEncoder { return json.NewEncoder(w) },
+		NewDecoder: func(r io.Reader) MapDecoder { return json.NewDecoder(r) },
+	}
+)
+
+// persistedEntry is the serialized form of a map entry. The value is stored
+// with a presence flag rather than by pointer, since encoding/gob does not
+// distinguish a nil pointer from a pointer to the zero value.
+type persistedEntry[K comparable, V any] struct {
+	Key     K    `json:"key"`
+	Present bool `json:"present"`
+	Value   V    `json:"value"`
+}
+
+// partitionedMap is implemented by the maps that can copy their entries
+// one partition at a time, so that the entries can be serialized without
+// holding a lock or copying the whole map.
+type partitionedMap[K comparable, V any] interface {
+	partitions() iter.Seq[[]mapEntry[K, V]]
+}
+
+// SaveMap writes the entries of the map to w as a stream of key-value pairs,
+// serialized using the given codec. The entries are written one at a time,
+// so the map is not copied in memory. A ShardedMap is saved shard by shard,
+// copying one shard at a time, so the shard locks are not held while writing.
+//
+// The saved entries are weakly consistent with concurrent updates of the map,
+// like All. Use a Snapshot to save a consistent copy of the map.
+func SaveMap[K comparable, V any](w io.Writer, m Map[K, V], codec MapCodec) error {
+	encoder := codec.NewEncoder(w)
+	if pm, ok := m.(partitionedMap[K, V]); ok {
+		for entries := range pm.partitions() {
+			for _, entry := range entries {
+				if err := encodeEntry(encoder, entry.key, entry.value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return saveEntries(encoder, m.All())
+}
+
+// SaveSnapshot writes the entries of the snapshot to w as a stream of
+// key-value pairs, serialized using the given codec.
+func SaveSnapshot[K comparable, V any](w io.Writer, snapshot *MapSnapshot[K, V], codec MapCodec) error {
+	return saveEntries(codec.NewEncoder(w), snapshot.All())
+}
+
+// LoadMap reads a stream of key-value pairs, written by SaveMap using the
+// same codec, from r and puts them into the given map of any implementation.
+// The entries are read and stored one at a time. Existing mappings of the
+// map are retained, unless overwritten by the loaded entries.
+func LoadMap[K comparable, V any](r io.Reader, m Map[K, V], codec MapCodec) error {
+	decoder := codec.NewDecoder(r)
+	for {
+		var entry persistedEntry[K, V]
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("async: failed to decode map entry: %w", err)
+		}
+		var value *V
+		if entry.Present {
+			value = &entry.Value
+		}
+		m.Put(entry.Key, value)
+	}
+}
+
+func saveEntries[K comparable, V any](encoder MapEncoder, entries iter.Seq2[K, *V]) error {
+	for key, value := range entries {
+		if err := encodeEntry(encoder, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeEntry[K comparable, V any](encoder MapEncoder, key K, value *V) error {
+	entry := persistedEntry[K, V]{Key: key}
+	if value != nil {
+		entry.Present, entry.Value = true, *value
+	}
+	if err := encoder.Encode(entry); err != nil {
+		return fmt.Errorf("async: failed to encode map entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package async
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/reugn/async/internal/assert"
+	"github.com/reugn/async/internal/ptr"
+)
+
+func TestSaveLoadMap(t *testing.T) {
+	t.Parallel()
+
+	codecs := map[string]MapCodec{
+		"gob":  GobCodec,
+		"json": JSONCodec,
+	}
+	for codecName, codec := range codecs {
+		for _, tt := range prepareTestMaps() {
+			t.Run(codecName+"/"+tt.name, func(t *testing.T) {
+				t.Parallel()
+				tt.m.Put(4, nil)
+				tt.m.Put(6, ptr.Of(""))
+
+				var buf bytes.Buffer
+				err := SaveMap(&buf, tt.m, codec)
+				assert.IsNil(t, err)
+
+				for _, target := range prepareTestMaps() {
+					target.m.Clear()
+					target.m.Put(5, ptr.Of("e"))
+					err = LoadMap(bytes.NewReader(buf.Bytes()), target.m, codec)
+					assert.IsNil(t, err)
+
+					assert.Equal(t, target.m.Size(), 6)
+					assert.Equal(t, target.m.Get(1), ptr.Of("a"))
+					assert.Equal(t, target.m.Get(3), ptr.Of("c"))
+					assert.IsNil(t, target.m.Get(4))
+					assert.Equal(t, target.m.Get(5), ptr.Of("e"))
+					assert.Equal(t, target.m.Get(6), ptr.Of(""))
+				}
+			})
+		}
+	}
+}
+
+func TestSaveLoadMap_Large(t *testing.T) {
+	t.Parallel()
+
+	type value struct {
+		Name  string
+		Count int
+	}
+	m := NewShardedMap[string, value](8)
+	for i := 0; i < 10000; i++ {
+		m.Put(strconv.Itoa(i), &value{Name: "v" + strconv.Itoa(i), Count: i})
+	}
+	m.Put("zero", &value{})
+
+	var buf bytes.Buffer
+	err := SaveMap(&buf, m, GobCodec)
+	assert.IsNil(t, err)
+
+	loaded := NewLockFreeMap[string, value]()
+	err = LoadMap(&buf, loaded, GobCodec)
+	assert.IsNil(t, err)
+	assert.Equal(t, maps.Collect(loaded.All()), maps.Collect(m.All()))
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	t.Parallel()
+
+	m := NewOrderedMap[int, string]()
+	putValues(m)
+	snapshot := m.Snapshot()
+	m.Clear()
+
+	var buf bytes.Buffer
+	err := SaveSnapshot(&buf, snapshot, JSONCodec)
+	assert.IsNil(t, err)
+	assert.Equal(t, buf.String(), `{"key":1,"present":true,"value":"a"}
+{"key":2,"present":true,"value":"b"}
+{"key":3,"present":true,"value":"c"}
+`)
+
+	err = LoadMap(&buf, m, JSONCodec)
+	assert.IsNil(t, err)
+	assert.Equal(t, m.KeySet(), []int{1, 2, 3})
+}
+
+func TestLoadMap_Error(t *testing.T) {
+	t.Parallel()
+
+	m := NewSynchronizedMap[int, string]()
+	err := LoadMap(strings.NewReader(`{"key":1,"present":true,"value":"a"}{"key":"b"}`), m, JSONCodec)
+	assert.ErrorContains(t, err, "async: failed to decode map entry")
+	assert.Equal(t, m.Get(1), ptr.Of("a"))
+
+	err = SaveMap(errWriter{}, m, GobCodec)
+	assert.ErrorIs(t, err, errWrite)
+}
+
+var errWrite = errors.New("write error")
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
@@ -2,32 +2,54 @@ package async
 
 import (
 	"fmt"
-	"hash/fnv"
 	"iter"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
+// maxShardedMapShards is the upper bound for the number of shards reached
+// by the automatic growth of a ShardedMap.
+const maxShardedMapShards = 1 << 16
+
 // ShardedMap implements the async.Map interface in a thread-safe manner,
 // delegating load/store operations to one of the underlying async.SynchronizedMaps
 // (shards), using a key hash to calculate the shard number.
 // A ShardedMap must not be copied.
+//
+// The number of shards can be doubled online using Grow, or automatically when
+// a shard exceeds the size limit set by SetMaxShardSize. Each shard is split
+// into two shards of the new layout, and the entries are migrated one shard at
+// a time on first access, so the map is never locked as a whole.
+//
+// PutAll and RemoveAll lock the shards of all of the given keys together,
+// while RemoveIf, ReplaceAll and Snapshot lock all of the shards, so these
+// operations are atomic and Snapshot is consistent. The other operations
+// on the whole map, such as Clear, Size and All, proceed shard by shard.
 type ShardedMap[K comparable, V any] struct {
-	shards   uint64
-	shardMap []*SynchronizedMap[K, V]
-	hashFunc func(K) uint64
+	layout       atomic.Pointer[shardLayout[K, V]]
+	hashFunc     func(K) uint64
+	maxShardSize atomic.Int64
 }
 
 var _ Map[int, any] = (*ShardedMap[int, any])(nil)
 
+// ShardStats represents a point-in-time snapshot of ShardedMap shard statistics.
+type ShardStats struct {
+	// Size is the number of mappings in the shard.
+	Size int
+	// Operations is the number of operations that acquired the shard lock.
+	Operations int64
+	// Contended is the number of operations that had to wait for the shard lock.
+	Contended int64
+}
+
 // NewShardedMap returns a new ShardedMap, where shards is the number of partitions for this
-// map. It uses the 64-bit FNV-1a hash function to calculate the shard number for a key.
+// map. It uses a hash/maphash based function with a random per-map seed to calculate
+// the shard number for a key of any comparable type.
 // If the shards argument is not positive, NewShardedMap will panic.
 func NewShardedMap[K comparable, V any](shards int) *ShardedMap[K, V] {
-	return NewShardedMapWithHash[K, V](shards, func(key K) uint64 {
-		h := fnv.New64a()
-		_, _ = fmt.Fprint(h, key)
-		return h.Sum64()
-	})
+	return NewShardedMapWithHash[K, V](shards, newHashFunc[K]())
 }
 
 // NewShardedMapWithHash returns a new ShardedMap, where shards is the number of partitions
@@ -40,53 +62,105 @@ func NewShardedMapWithHash[K comparable, V any](shards int, hashFunc func(K) uin
 	if hashFunc == nil {
 		panic("hashFunc is nil")
 	}
-	shardMap := make([]*SynchronizedMap[K, V], shards)
-	for i := 0; i < shards; i++ {
-		shardMap[i] = NewSynchronizedMap[K, V]()
-	}
-	return &ShardedMap[K, V]{
-		shards:   uint64(shards),
-		shardMap: shardMap,
-		hashFunc: hashFunc,
-	}
+	sm := &ShardedMap[K, V]{hashFunc: hashFunc}
+	sm.layout.Store(newShardLayout[K, V](shards))
+	return sm
 }
 
 // Clear removes all of the mappings from this map.
 func (sm *ShardedMap[K, V]) Clear() {
-	for _, shard := range sm.shardMap {
-		shard.Clear()
+	for {
+		layout := sm.layout.Load()
+		sm.migrateAll(layout)
+		for _, shard := range layout.shards {
+			shard.Clear()
+		}
+		// retry if the mappings were migrated to a new layout meanwhile
+		if sm.layout.Load() == layout {
+			return
+		}
 	}
 }
 
+// CompareAndDelete removes the mapping for the specified key only if it
+// is currently mapped to oldValue (compared by pointer).
+// It returns true if the mapping was removed.
+func (sm *ShardedMap[K, V]) CompareAndDelete(key K, oldValue *V) bool {
+	shard := sm.lock(key)
+	defer shard.Unlock()
+	return shard.compareAndDelete(key, oldValue)
+}
+
+// CompareAndSwap replaces the value for the specified key with newValue
+// only if it is currently mapped to oldValue (compared by pointer).
+// It returns true if the value was replaced.
+func (sm *ShardedMap[K, V]) CompareAndSwap(key K, oldValue, newValue *V) bool {
+	shard := sm.lock(key)
+	defer shard.Unlock()
+	return shard.compareAndSwap(key, oldValue, newValue)
+}
+
+// Compute attempts to compute a mapping for the specified key and its
+// current value, or nil if there is no current mapping, and enters it
+// into the map. If the remapping function returns nil, the mapping is
+// removed. It returns the new value or nil if none.
+func (sm *ShardedMap[K, V]) Compute(key K, remappingFunction func(K, *V) *V) *V {
+	shard := sm.lock(key)
+	defer sm.unlockAndGrow(shard)
+	return shard.compute(key, remappingFunction)
+}
+
 // ComputeIfAbsent attempts to compute a value using the given mapping
 // function and enters it into the map, if the specified key is not
 // already associated with a value.
 func (sm *ShardedMap[K, V]) ComputeIfAbsent(key K, mappingFunction func(K) *V) *V {
-	return sm.shard(key).ComputeIfAbsent(key, mappingFunction)
+	shard := sm.lock(key)
+	defer sm.unlockAndGrow(shard)
+	return shard.computeIfAbsent(key, mappingFunction)
+}
+
+// ComputeIfPresent attempts to compute a new mapping given the key and
+// its current value, if the specified key is associated with a value.
+// If the remapping function returns nil, the mapping is removed.
+// It returns the new value or nil if none.
+func (sm *ShardedMap[K, V]) ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	shard := sm.lock(key)
+	defer shard.Unlock()
+	return shard.computeIfPresent(key, remappingFunction)
 }
 
 // ContainsKey returns true if this map contains a mapping for the
 // specified key.
 func (sm *ShardedMap[K, V]) ContainsKey(key K) bool {
-	return sm.shard(key).ContainsKey(key)
+	shard := sm.rlock(key)
+	defer shard.RUnlock()
+	_, ok := shard.store[key]
+	return ok
 }
 
 // Get returns the value to which the specified key is mapped, or nil if
 // this map contains no mapping for the key.
 func (sm *ShardedMap[K, V]) Get(key K) *V {
-	return sm.shard(key).Get(key)
+	shard := sm.rlock(key)
+	defer shard.RUnlock()
+	return shard.store[key]
 }
 
 // GetOrDefault returns the value to which the specified key is mapped, or
 // defaultValue if this map contains no mapping for the key.
 func (sm *ShardedMap[K, V]) GetOrDefault(key K, defaultValue *V) *V {
-	return sm.shard(key).GetOrDefault(key, defaultValue)
+	shard := sm.rlock(key)
+	defer shard.RUnlock()
+	if value, ok := shard.store[key]; ok {
+		return value
+	}
+	return defaultValue
 }
 
 // IsEmpty returns true if this map contains no key-value mappings.
 func (sm *ShardedMap[K, V]) IsEmpty() bool {
-	for _, shard := range sm.shardMap {
-		if !shard.IsEmpty() {
+	for size := range collectShards(sm, shardSize) {
+		if size > 0 {
 			return false
 		}
 	}
@@ -96,48 +170,162 @@ func (sm *ShardedMap[K, V]) IsEmpty() bool {
 // KeySet returns a slice of the keys contained in this map.
 func (sm *ShardedMap[K, V]) KeySet() []K {
 	var keys []K
-	for _, shard := range sm.shardMap {
-		keys = append(keys, shard.KeySet()...)
+	for entries := range sm.partitions() {
+		for _, entry := range entries {
+			keys = append(keys, entry.key)
+		}
 	}
 	return keys
 }
 
+// Merge associates the specified key with the given value if it is not
+// already associated with a value. Otherwise, replaces the value with the
+// result of the remapping function applied to the current and the given
+// values, or removes the mapping if the result is nil.
+// It returns the new value or nil if none.
+func (sm *ShardedMap[K, V]) Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	shard := sm.lock(key)
+	defer sm.unlockAndGrow(shard)
+	return shard.merge(key, value, remappingFunction)
+}
+
 // Put associates the specified value with the specified key in this map.
 func (sm *ShardedMap[K, V]) Put(key K, value *V) {
-	sm.shard(key).Put(key, value)
+	shard := sm.lock(key)
+	defer sm.unlockAndGrow(shard)
+	shard.put(key, value)
+}
+
+// PutAll copies all of the key-value pairs from the given sequence
+// to this map.
+func (sm *ShardedMap[K, V]) PutAll(entries iter.Seq2[K, *V]) {
+	collected := collectEntries(entries)
+	hashes := make([]uint64, len(collected))
+	for i, entry := range collected {
+		hashes[i] = sm.hashFunc(entry.key)
+	}
+	shardOf, locked := sm.lockShards(hashes)
+	for i, entry := range collected {
+		shardOf[i].store[entry.key] = entry.value
+	}
+	for _, shard := range locked {
+		sm.unlockAndGrow(shard)
+	}
+}
+
+// PutIfAbsent associates the specified key with the given value if it is
+// not already associated with a value. It returns the current value,
+// or nil if the given value was stored.
+func (sm *ShardedMap[K, V]) PutIfAbsent(key K, value *V) *V {
+	shard := sm.lock(key)
+	defer sm.unlockAndGrow(shard)
+	return shard.putIfAbsent(key, value)
 }
 
 // Remove removes the mapping for a key from this map if it is present,
 // returning the previous value or nil if none.
 func (sm *ShardedMap[K, V]) Remove(key K) *V {
-	return sm.shard(key).Remove(key)
+	shard := sm.lock(key)
+	defer shard.Unlock()
+	return shard.remove(key)
+}
+
+// RemoveAll removes the mappings for all of the given keys from this
+// map. It returns the number of removed mappings.
+func (sm *ShardedMap[K, V]) RemoveAll(keys iter.Seq[K]) int {
+	collected := slices.Collect(keys)
+	hashes := make([]uint64, len(collected))
+	for i, key := range collected {
+		hashes[i] = sm.hashFunc(key)
+	}
+	shardOf, locked := sm.lockShards(hashes)
+	defer releaseShards(locked, true)
+	var removed int
+	for i, key := range collected {
+		if _, ok := shardOf[i].store[key]; ok {
+			delete(shardOf[i].store, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RemoveIf removes all of the mappings that satisfy the given predicate.
+// It returns the number of removed mappings.
+func (sm *ShardedMap[K, V]) RemoveIf(predicate func(K, *V) bool) int {
+	shards := sm.lockAll(true)
+	defer releaseShards(shards, true)
+	var removed int
+	for _, shard := range shards {
+		removed += shard.removeIf(predicate)
+	}
+	return removed
+}
+
+// Replace replaces the value for the specified key only if it is
+// currently mapped to some value. It returns the previous value,
+// or nil if there was no mapping for the key.
+func (sm *ShardedMap[K, V]) Replace(key K, value *V) *V {
+	shard := sm.lock(key)
+	defer shard.Unlock()
+	return shard.replace(key, value)
+}
+
+// ReplaceAll replaces the value of each mapping with the result of the
+// given function applied to the mapping. If the function returns nil,
+// the mapping is removed.
+func (sm *ShardedMap[K, V]) ReplaceAll(function func(K, *V) *V) {
+	shards := sm.lockAll(true)
+	defer releaseShards(shards, true)
+	for _, shard := range shards {
+		shard.replaceAll(function)
+	}
 }
 
 // Size returns the number of key-value mappings in this map.
 func (sm *ShardedMap[K, V]) Size() int {
 	var size int
-	for _, shard := range sm.shardMap {
-		size += shard.Size()
+	for shardSize := range collectShards(sm, shardSize) {
+		size += shardSize
 	}
 	return size
 }
 
+// Snapshot returns an immutable copy of the mappings in this map.
+func (sm *ShardedMap[K, V]) Snapshot() *MapSnapshot[K, V] {
+	shards := sm.lockAll(false)
+	defer releaseShards(shards, false)
+	var size int
+	for _, shard := range shards {
+		size += len(shard.store)
+	}
+	snapshot := newMapSnapshot[K, V](size)
+	for _, shard := range shards {
+		shard.snapshot(snapshot)
+	}
+	return snapshot
+}
+
 // Values returns a slice of the values contained in this map.
 func (sm *ShardedMap[K, V]) Values() []*V {
 	var values []*V
-	for _, shard := range sm.shardMap {
-		values = append(values, shard.Values()...)
+	for entries := range sm.partitions() {
+		for _, entry := range entries {
+			values = append(values, entry.value)
+		}
 	}
 	return values
 }
 
 // All returns an iterator of all key-value pairs in this map.
 // The order of the pairs is not specified.
+// The mappings are copied one shard at a time, so no lock is held while
+// yielding.
 func (sm *ShardedMap[K, V]) All() iter.Seq2[K, *V] {
 	return func(yield func(K, *V) bool) {
-		for _, shard := range sm.shardMap {
-			for key, value := range shard.All() {
-				if !yield(key, value) {
+		for entries := range sm.partitions() {
+			for _, entry := range entries {
+				if !yield(entry.key, entry.value) {
 					return
 				}
 			}
@@ -145,14 +333,286 @@ func (sm *ShardedMap[K, V]) All() iter.Seq2[K, *V] {
 	}
 }
 
-// shard returns an underlying synchronized map for the key.
-func (sm *ShardedMap[K, V]) shard(key K) Map[K, V] {
-	return sm.shardMap[sm.hashFunc(key)%sm.shards]
+// Shards returns the current number of shards in this map.
+func (sm *ShardedMap[K, V]) Shards() int {
+	return len(sm.layout.Load().shards)
+}
+
+// Stats returns the statistics of each shard in this map, which can be used
+// to decide whether to grow the number of shards.
+func (sm *ShardedMap[K, V]) Stats() []ShardStats {
+	return slices.Collect(collectShards(sm, func(shard *mapShard[K, V]) ShardStats {
+		return ShardStats{
+			Size:       len(shard.store),
+			Operations: shard.operations.Load(),
+			Contended:  shard.contended.Load(),
+		}
+	}))
+}
+
+// Grow doubles the number of shards in this map. The mappings are migrated
+// to the new shards incrementally by the subsequent operations, except for
+// a growth still in progress, which is completed first.
+func (sm *ShardedMap[K, V]) Grow() {
+	for {
+		layout := sm.layout.Load()
+		if sm.grow(layout) {
+			return
+		}
+	}
+}
+
+// SetMaxShardSize enables the automatic growth of the number of shards when
+// the number of mappings in a shard exceeds maxShardSize. A non-positive
+// value disables the automatic growth, which is the default.
+func (sm *ShardedMap[K, V]) SetMaxShardSize(maxShardSize int) {
+	sm.maxShardSize.Store(int64(maxShardSize))
+}
+
+// grow replaces the given layout with a new layout of twice as many shards.
+// It returns false if the layout was replaced concurrently.
+func (sm *ShardedMap[K, V]) grow(layout *shardLayout[K, V]) bool {
+	sm.migrateAll(layout)
+	next := newShardLayout[K, V](2 * len(layout.shards))
+	next.pending.Store(int64(len(layout.shards)))
+	next.prev.Store(layout)
+	return sm.layout.CompareAndSwap(layout, next)
+}
+
+// partitions returns an iterator of the entries of each shard, copied
+// one shard at a time.
+func (sm *ShardedMap[K, V]) partitions() iter.Seq[[]mapEntry[K, V]] {
+	return collectShards(sm, func(shard *mapShard[K, V]) []mapEntry[K, V] {
+		return shard.entries()
+	})
+}
+
+// collectShards returns an iterator of the results of the collect function
+// applied to each shard of the current layout, which is read-locked for the
+// duration of the call. The results are yielded with no lock held.
+//
+// A shard moved by a concurrent growth is followed into the layout it was
+// moved to, where the mappings of the i-th shard of a layout of n shards
+// are split between the i-th and the (i+n)-th shards. Thus, each mapping
+// present for the whole iteration is collected exactly once.
+func collectShards[K comparable, V, R any](sm *ShardedMap[K, V],
+	collect func(*mapShard[K, V]) R) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		layout := sm.layout.Load()
+		sm.migrateAll(layout)
+		var visit func(layout *shardLayout[K, V], i int) bool
+		visit = func(layout *shardLayout[K, V], i int) bool {
+			shard := layout.shards[i]
+			shard.RLock()
+			if next := shard.movedTo; next != nil {
+				shard.RUnlock()
+				return visit(next, i) && visit(next, i+len(layout.shards))
+			}
+			result := collect(shard)
+			shard.RUnlock()
+			return yield(result)
+		}
+		for i := range layout.shards {
+			if !visit(layout, i) {
+				return
+			}
+		}
+	}
+}
+
+func shardSize[K comparable, V any](shard *mapShard[K, V]) int {
+	return len(shard.store)
+}
+
+// lock returns the write-locked shard for the key.
+func (sm *ShardedMap[K, V]) lock(key K) *mapShard[K, V] {
+	return sm.acquire(key, true)
+}
+
+// rlock returns the read-locked shard for the key.
+func (sm *ShardedMap[K, V]) rlock(key K) *mapShard[K, V] {
+	return sm.acquire(key, false)
+}
+
+// acquire locks and returns the shard for the key in the current layout.
+// The mappings of the key are migrated from the previous layout first.
+func (sm *ShardedMap[K, V]) acquire(key K, write bool) *mapShard[K, V] {
+	hash := sm.hashFunc(key)
+	for {
+		layout := sm.layout.Load()
+		if prev := layout.prev.Load(); prev != nil {
+			sm.migrate(layout, prev, int(hash%uint64(len(prev.shards))))
+		}
+		shard := layout.shards[hash%uint64(len(layout.shards))]
+		shard.acquire(write)
+		if shard.movedTo == nil {
+			return shard
+		}
+		// the layout was replaced after loading
+		shard.release(write)
+	}
+}
+
+// lockShards write-locks the shards of the current layout for the given
+// key hashes in ascending order. It returns the shard for each of the hashes
+// and the locked shards.
+func (sm *ShardedMap[K, V]) lockShards(hashes []uint64) ([]*mapShard[K, V], []*mapShard[K, V]) {
+	for {
+		layout := sm.layout.Load()
+		sm.migrateAll(layout)
+		shards := uint64(len(layout.shards))
+		indices := make([]int, len(hashes))
+		shardOf := make([]*mapShard[K, V], len(hashes))
+		for i, hash := range hashes {
+			indices[i] = int(hash % shards)
+			shardOf[i] = layout.shards[indices[i]]
+		}
+		slices.Sort(indices)
+		indices = slices.Compact(indices)
+		locked := make([]*mapShard[K, V], len(indices))
+		for i, index := range indices {
+			locked[i] = layout.shards[index]
+		}
+		if acquireShards(locked, true) {
+			return shardOf, locked
+		}
+	}
+}
+
+// lockAll locks all of the shards of the current layout.
+func (sm *ShardedMap[K, V]) lockAll(write bool) []*mapShard[K, V] {
+	for {
+		layout := sm.layout.Load()
+		sm.migrateAll(layout)
+		if acquireShards(layout.shards, write) {
+			return layout.shards
+		}
+	}
+}
+
+// acquireShards locks the shards in order. If any of the shards has been
+// moved to a new layout meanwhile, it unlocks the shards and returns false.
+func acquireShards[K comparable, V any](shards []*mapShard[K, V], write bool) bool {
+	for _, shard := range shards {
+		shard.acquire(write)
+	}
+	for _, shard := range shards {
+		if shard.movedTo != nil {
+			releaseShards(shards, write)
+			return false
+		}
+	}
+	return true
+}
+
+// releaseShards unlocks the shards.
+func releaseShards[K comparable, V any](shards []*mapShard[K, V], write bool) {
+	for _, shard := range shards {
+		shard.release(write)
+	}
+}
+
+// unlockAndGrow unlocks the shard and starts the growth of the number of
+// shards if the shard exceeds the maximum size.
+func (sm *ShardedMap[K, V]) unlockAndGrow(shard *mapShard[K, V]) {
+	size := len(shard.store)
+	shard.Unlock()
+	maxShardSize := sm.maxShardSize.Load()
+	if maxShardSize <= 0 || int64(size) <= maxShardSize {
+		return
+	}
+	if layout := sm.layout.Load(); layout == shard.layout && len(layout.shards) < maxShardedMapShards {
+		sm.grow(layout)
+	}
+}
+
+// migrateAll completes the migration of the mappings to the given layout.
+func (sm *ShardedMap[K, V]) migrateAll(layout *shardLayout[K, V]) {
+	if prev := layout.prev.Load(); prev != nil {
+		for i := range prev.shards {
+			sm.migrate(layout, prev, i)
+		}
+	}
+}
+
+// migrate moves the mappings of the i-th shard of the previous layout
+// to the i-th and the (i+n)-th shards of the layout, where n is the number
+// of shards in the previous layout.
+func (sm *ShardedMap[K, V]) migrate(layout, prev *shardLayout[K, V], i int) {
+	source := prev.shards[i]
+	source.Lock()
+	defer source.Unlock()
+	if source.movedTo != nil {
+		return
+	}
+
+	// the target shards are not accessed until the source shard is moved
+	shards := uint64(len(layout.shards))
+	for key, value := range source.store {
+		layout.shards[sm.hashFunc(key)%shards].store[key] = value
+	}
+	source.store = nil
+	source.movedTo = layout
+	if layout.pending.Add(-1) == 0 {
+		layout.prev.Store(nil)
+	}
+}
+
+// shardLayout is a set of shards. The mappings of the previous layout
+// are migrated to the current one shard by shard after growth.
+type shardLayout[K comparable, V any] struct {
+	shards  []*mapShard[K, V]
+	prev    atomic.Pointer[shardLayout[K, V]]
+	pending atomic.Int64 // the number of shards to migrate from prev
+}
+
+func newShardLayout[K comparable, V any](shards int) *shardLayout[K, V] {
+	layout := &shardLayout[K, V]{shards: make([]*mapShard[K, V], shards)}
+	for i := range layout.shards {
+		layout.shards[i] = &mapShard[K, V]{
+			SynchronizedMap: NewSynchronizedMap[K, V](),
+			layout:          layout,
+		}
+	}
+	return layout
+}
+
+// mapShard is a SynchronizedMap with contention statistics.
+type mapShard[K comparable, V any] struct {
+	*SynchronizedMap[K, V]
+	layout     *shardLayout[K, V]
+	movedTo    *shardLayout[K, V] // the layout the shard was moved to, guarded by the shard lock
+	operations atomic.Int64
+	contended  atomic.Int64
+}
+
+func (s *mapShard[K, V]) acquire(write bool) {
+	s.operations.Add(1)
+	if write {
+		if !s.TryLock() {
+			s.contended.Add(1)
+			s.Lock()
+		}
+	} else if !s.TryRLock() {
+		s.contended.Add(1)
+		s.RLock()
+	}
+}
+
+func (s *mapShard[K, V]) release(write bool) {
+	if write {
+		s.Unlock()
+	} else {
+		s.RUnlock()
+	}
 }
 
 // SynchronizedMap implements the async.Map interface in a thread-safe manner,
 // delegating load/store operations to a Go map and using a sync.RWMutex
 // for synchronization.
+//
+// The bulk operations are atomic, and Snapshot is consistent, as they are
+// performed under the lock.
 type SynchronizedMap[K comparable, V any] struct {
 	sync.RWMutex
 	store map[K]*V
@@ -174,18 +634,51 @@ func (sync *SynchronizedMap[K, V]) Clear() {
 	sync.store = make(map[K]*V)
 }
 
+// CompareAndDelete removes the mapping for the specified key only if it
+// is currently mapped to oldValue (compared by pointer).
+// It returns true if the mapping was removed.
+func (sync *SynchronizedMap[K, V]) CompareAndDelete(key K, oldValue *V) bool {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.compareAndDelete(key, oldValue)
+}
+
+// CompareAndSwap replaces the value for the specified key with newValue
+// only if it is currently mapped to oldValue (compared by pointer).
+// It returns true if the value was replaced.
+func (sync *SynchronizedMap[K, V]) CompareAndSwap(key K, oldValue, newValue *V) bool {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.compareAndSwap(key, oldValue, newValue)
+}
+
+// Compute attempts to compute a mapping for the specified key and its
+// current value, or nil if there is no current mapping, and enters it
+// into the map. If the remapping function returns nil, the mapping is
+// removed. It returns the new value or nil if none.
+func (sync *SynchronizedMap[K, V]) Compute(key K, remappingFunction func(K, *V) *V) *V {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.compute(key, remappingFunction)
+}
+
 // ComputeIfAbsent attempts to compute a value using the given mapping
 // function and enters it into the map, if the specified key is not
 // already associated with a value.
 func (sync *SynchronizedMap[K, V]) ComputeIfAbsent(key K, mappingFunction func(K) *V) *V {
 	sync.Lock()
 	defer sync.Unlock()
-	value, ok := sync.store[key]
-	if !ok {
-		value = mappingFunction(key)
-		sync.store[key] = value
-	}
-	return value
+	return sync.computeIfAbsent(key, mappingFunction)
+}
+
+// ComputeIfPresent attempts to compute a new mapping given the key and
+// its current value, if the specified key is associated with a value.
+// If the remapping function returns nil, the mapping is removed.
+// It returns the new value or nil if none.
+func (sync *SynchronizedMap[K, V]) ComputeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.computeIfPresent(key, remappingFunction)
 }
 
 // ContainsKey returns true if this map contains a mapping for the
@@ -235,11 +728,40 @@ func (sync *SynchronizedMap[K, V]) KeySet() []K {
 	return keys
 }
 
+// Merge associates the specified key with the given value if it is not
+// already associated with a value. Otherwise, replaces the value with the
+// result of the remapping function applied to the current and the given
+// values, or removes the mapping if the result is nil.
+// It returns the new value or nil if none.
+func (sync *SynchronizedMap[K, V]) Merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.merge(key, value, remappingFunction)
+}
+
 // Put associates the specified value with the specified key in this map.
 func (sync *SynchronizedMap[K, V]) Put(key K, value *V) {
 	sync.Lock()
 	defer sync.Unlock()
-	sync.store[key] = value
+	sync.put(key, value)
+}
+
+// PutAll copies all of the key-value pairs from the given sequence
+// to this map.
+func (sync *SynchronizedMap[K, V]) PutAll(entries iter.Seq2[K, *V]) {
+	collected := collectEntries(entries)
+	sync.Lock()
+	defer sync.Unlock()
+	sync.putAll(collected)
+}
+
+// PutIfAbsent associates the specified key with the given value if it is
+// not already associated with a value. It returns the current value,
+// or nil if the given value was stored.
+func (sync *SynchronizedMap[K, V]) PutIfAbsent(key K, value *V) *V {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.putIfAbsent(key, value)
 }
 
 // Remove removes the mapping for a key from this map if it is present,
@@ -247,11 +769,42 @@ func (sync *SynchronizedMap[K, V]) Put(key K, value *V) {
 func (sync *SynchronizedMap[K, V]) Remove(key K) *V {
 	sync.Lock()
 	defer sync.Unlock()
-	value, ok := sync.store[key]
-	if ok {
-		delete(sync.store, key)
-	}
-	return value
+	return sync.remove(key)
+}
+
+// RemoveAll removes the mappings for all of the given keys from this
+// map. It returns the number of removed mappings.
+func (sync *SynchronizedMap[K, V]) RemoveAll(keys iter.Seq[K]) int {
+	collected := slices.Collect(keys)
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.removeAll(collected)
+}
+
+// RemoveIf removes all of the mappings that satisfy the given predicate.
+// It returns the number of removed mappings.
+func (sync *SynchronizedMap[K, V]) RemoveIf(predicate func(K, *V) bool) int {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.removeIf(predicate)
+}
+
+// Replace replaces the value for the specified key only if it is
+// currently mapped to some value. It returns the previous value,
+// or nil if there was no mapping for the key.
+func (sync *SynchronizedMap[K, V]) Replace(key K, value *V) *V {
+	sync.Lock()
+	defer sync.Unlock()
+	return sync.replace(key, value)
+}
+
+// ReplaceAll replaces the value of each mapping with the result of the
+// given function applied to the mapping. If the function returns nil,
+// the mapping is removed.
+func (sync *SynchronizedMap[K, V]) ReplaceAll(function func(K, *V) *V) {
+	sync.Lock()
+	defer sync.Unlock()
+	sync.replaceAll(function)
 }
 
 // Size returns the number of key-value mappings in this map.
@@ -261,6 +814,15 @@ func (sync *SynchronizedMap[K, V]) Size() int {
 	return len(sync.store)
 }
 
+// Snapshot returns an immutable copy of the mappings in this map.
+func (sync *SynchronizedMap[K, V]) Snapshot() *MapSnapshot[K, V] {
+	sync.RLock()
+	defer sync.RUnlock()
+	snapshot := newMapSnapshot[K, V](len(sync.store))
+	sync.snapshot(snapshot)
+	return snapshot
+}
+
 // Values returns a slice of the values contained in this map.
 func (sync *SynchronizedMap[K, V]) Values() []*V {
 	sync.RLock()
@@ -285,3 +847,152 @@ func (sync *SynchronizedMap[K, V]) All() iter.Seq2[K, *V] {
 		}
 	}
 }
+
+// The unexported counterparts of the operations below expect the caller
+// to hold the lock. They are shared with ShardedMap, which locks its
+// shards directly.
+
+func (sync *SynchronizedMap[K, V]) compareAndDelete(key K, oldValue *V) bool {
+	value, ok := sync.store[key]
+	if ok && value == oldValue {
+		delete(sync.store, key)
+		return true
+	}
+	return false
+}
+
+func (sync *SynchronizedMap[K, V]) compareAndSwap(key K, oldValue, newValue *V) bool {
+	value, ok := sync.store[key]
+	if ok && value == oldValue {
+		sync.store[key] = newValue
+		return true
+	}
+	return false
+}
+
+func (sync *SynchronizedMap[K, V]) compute(key K, remappingFunction func(K, *V) *V) *V {
+	value := remappingFunction(key, sync.store[key])
+	if value == nil {
+		delete(sync.store, key)
+	} else {
+		sync.store[key] = value
+	}
+	return value
+}
+
+func (sync *SynchronizedMap[K, V]) computeIfAbsent(key K, mappingFunction func(K) *V) *V {
+	value, ok := sync.store[key]
+	if !ok {
+		value = mappingFunction(key)
+		sync.store[key] = value
+	}
+	return value
+}
+
+func (sync *SynchronizedMap[K, V]) computeIfPresent(key K, remappingFunction func(K, *V) *V) *V {
+	value, ok := sync.store[key]
+	if !ok {
+		return nil
+	}
+	value = remappingFunction(key, value)
+	if value == nil {
+		delete(sync.store, key)
+	} else {
+		sync.store[key] = value
+	}
+	return value
+}
+
+func (sync *SynchronizedMap[K, V]) merge(key K, value *V, remappingFunction func(*V, *V) *V) *V {
+	current, ok := sync.store[key]
+	if ok {
+		value = remappingFunction(current, value)
+		if value == nil {
+			delete(sync.store, key)
+			return nil
+		}
+	}
+	sync.store[key] = value
+	return value
+}
+
+func (sync *SynchronizedMap[K, V]) put(key K, value *V) {
+	sync.store[key] = value
+}
+
+func (sync *SynchronizedMap[K, V]) putIfAbsent(key K, value *V) *V {
+	current, ok := sync.store[key]
+	if ok {
+		return current
+	}
+	sync.store[key] = value
+	return nil
+}
+
+func (sync *SynchronizedMap[K, V]) remove(key K) *V {
+	value, ok := sync.store[key]
+	if ok {
+		delete(sync.store, key)
+	}
+	return value
+}
+
+func (sync *SynchronizedMap[K, V]) replace(key K, value *V) *V {
+	previous, ok := sync.store[key]
+	if ok {
+		sync.store[key] = value
+	}
+	return previous
+}
+
+func (sync *SynchronizedMap[K, V]) putAll(entries []mapEntry[K, V]) {
+	for _, entry := range entries {
+		sync.store[entry.key] = entry.value
+	}
+}
+
+func (sync *SynchronizedMap[K, V]) removeAll(keys []K) int {
+	var removed int
+	for _, key := range keys {
+		if _, ok := sync.store[key]; ok {
+			delete(sync.store, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (sync *SynchronizedMap[K, V]) removeIf(predicate func(K, *V) bool) int {
+	var removed int
+	for key, value := range sync.store {
+		if predicate(key, value) {
+			delete(sync.store, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (sync *SynchronizedMap[K, V]) replaceAll(function func(K, *V) *V) {
+	for key, value := range sync.store {
+		if value = function(key, value); value == nil {
+			delete(sync.store, key)
+		} else {
+			sync.store[key] = value
+		}
+	}
+}
+
+func (sync *SynchronizedMap[K, V]) entries() []mapEntry[K, V] {
+	entries := make([]mapEntry[K, V], 0, len(sync.store))
+	for key, value := range sync.store {
+		entries = append(entries, mapEntry[K, V]{key, value})
+	}
+	return entries
+}
+
+func (sync *SynchronizedMap[K, V]) snapshot(snapshot *MapSnapshot[K, V]) {
+	for key, value := range sync.store {
+		snapshot.add(key, value)
+	}
+}
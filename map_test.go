@@ -1,10 +1,14 @@
 package async
 
 import (
+	"context"
+	"iter"
 	"maps"
 	"runtime"
+	"slices"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -213,6 +217,171 @@ func TestMap_All(t *testing.T) {
 	}
 }
 
+func TestMap_CompareAndDelete(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			value := tt.m.Get(1)
+			assert.Equal(t, tt.m.CompareAndDelete(1, ptr.Of("a")), false)
+			assert.Equal(t, tt.m.CompareAndDelete(4, nil), false)
+			assert.Equal(t, tt.m.Size(), 3)
+			assert.Equal(t, tt.m.CompareAndDelete(1, value), true)
+			assert.Equal(t, tt.m.Size(), 2)
+			assert.IsNil(t, tt.m.Get(1))
+		})
+	}
+}
+
+func TestMap_CompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			value := tt.m.Get(1)
+			assert.Equal(t, tt.m.CompareAndSwap(1, ptr.Of("a"), ptr.Of("b")), false)
+			assert.Equal(t, tt.m.CompareAndSwap(4, nil, ptr.Of("d")), false)
+			assert.Equal(t, tt.m.Get(1), ptr.Of("a"))
+			assert.Equal(t, tt.m.CompareAndSwap(1, value, ptr.Of("e")), true)
+			assert.Equal(t, tt.m.Get(1), ptr.Of("e"))
+			assert.Equal(t, tt.m.Size(), 3)
+		})
+	}
+}
+
+func TestMap_Compute(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			appendX := func(_ int, v *string) *string {
+				if v == nil {
+					return ptr.Of("x")
+				}
+				return ptr.Of(*v + "x")
+			}
+			assert.Equal(t, tt.m.Compute(1, appendX), ptr.Of("ax"))
+			assert.Equal(t, tt.m.Compute(4, appendX), ptr.Of("x"))
+			assert.Equal(t, tt.m.Size(), 4)
+			assert.IsNil(t, tt.m.Compute(2, func(_ int, _ *string) *string { return nil }))
+			assert.IsNil(t, tt.m.Compute(5, func(_ int, _ *string) *string { return nil }))
+			assert.Equal(t, tt.m.Size(), 3)
+			assert.Equal(t, tt.m.ContainsKey(2), false)
+		})
+	}
+}
+
+func TestMap_ComputeIfPresent(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			appendX := func(_ int, v *string) *string {
+				return ptr.Of(*v + "x")
+			}
+			assert.Equal(t, tt.m.ComputeIfPresent(1, appendX), ptr.Of("ax"))
+			assert.IsNil(t, tt.m.ComputeIfPresent(4, appendX))
+			assert.Equal(t, tt.m.Size(), 3)
+			assert.IsNil(t, tt.m.ComputeIfPresent(2, func(_ int, _ *string) *string { return nil }))
+			assert.Equal(t, tt.m.Size(), 2)
+		})
+	}
+}
+
+func TestMap_Merge(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			concat := func(a, b *string) *string {
+				return ptr.Of(*a + *b)
+			}
+			assert.Equal(t, tt.m.Merge(1, ptr.Of("b"), concat), ptr.Of("ab"))
+			assert.Equal(t, tt.m.Merge(4, ptr.Of("d"), concat), ptr.Of("d"))
+			assert.Equal(t, tt.m.Size(), 4)
+			assert.IsNil(t, tt.m.Merge(2, ptr.Of("b"), func(_, _ *string) *string { return nil }))
+			assert.Equal(t, tt.m.Size(), 3)
+		})
+	}
+}
+
+func TestMap_PutIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.m.PutIfAbsent(1, ptr.Of("e")), ptr.Of("a"))
+			assert.IsNil(t, tt.m.PutIfAbsent(4, ptr.Of("d")))
+			assert.Equal(t, tt.m.Get(4), ptr.Of("d"))
+			assert.Equal(t, tt.m.Size(), 4)
+		})
+	}
+}
+
+func TestMap_Replace(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.m.Replace(1, ptr.Of("e")), ptr.Of("a"))
+			assert.Equal(t, tt.m.Get(1), ptr.Of("e"))
+			assert.IsNil(t, tt.m.Replace(4, ptr.Of("d")))
+			assert.Equal(t, tt.m.ContainsKey(4), false)
+			assert.Equal(t, tt.m.Size(), 3)
+		})
+	}
+}
+
+func TestMap_ComputeConcurrent(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			counters := newTestCounterMap(tt.m)
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 1000; j++ {
+						counters.Compute(j%4, func(_ int, v *int) *int {
+							if v == nil {
+								return ptr.Of(1)
+							}
+							return ptr.Of(*v + 1)
+						})
+						counters.Merge(4, ptr.Of(1), func(a, b *int) *int {
+							return ptr.Of(*a + *b)
+						})
+					}
+				}()
+			}
+			wg.Wait()
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, *counters.Get(i), 2000)
+			}
+			assert.Equal(t, *counters.Get(4), 8000)
+			assert.Equal(t, counters.Size(), 5)
+		})
+	}
+}
+
 func TestShardedMap_ConstructorArguments(t *testing.T) {
 	t.Parallel()
 
@@ -283,17 +452,1051 @@ func TestConcurrentMap_MemoryLeaks(t *testing.T) {
 	}
 }
 
+func TestConcurrentMap_ComputeIfAbsentOnce(t *testing.T) {
+	t.Parallel()
+
+	m := NewConcurrentMap[int, int]()
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			value := m.ComputeIfAbsent(1, func(_ int) *int {
+				calls.Add(1)
+				time.Sleep(5 * time.Millisecond)
+				return ptr.Of(1)
+			})
+			if *value != 1 {
+				t.Errorf("unexpected value: %d", *value)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, calls.Load(), int32(1))
+	assert.Equal(t, m.Size(), 1)
+}
+
+func TestConcurrentMap_ComputeIfAbsentNonBlocking(t *testing.T) {
+	t.Parallel()
+
+	m := NewConcurrentMap[int, int]()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.ComputeIfAbsent(1, func(_ int) *int {
+			<-release
+			return ptr.Of(1)
+		})
+	}()
+	time.Sleep(time.Millisecond)
+
+	// operations on the computed key and on unrelated keys do not block
+	assert.IsNil(t, m.Get(1))
+	assert.Equal(t, *m.ComputeIfAbsent(2, func(_ int) *int { return ptr.Of(2) }), 2)
+	assert.Equal(t, m.Size(), 1)
+
+	close(release)
+	<-done
+	assert.Equal(t, *m.Get(1), 1)
+	assert.Equal(t, m.Size(), 2)
+}
+
+func TestConcurrentMap_ComputeIfAbsentPanic(t *testing.T) {
+	t.Parallel()
+
+	m := NewConcurrentMap[int, int]()
+	assert.Panics(t, func() {
+		m.ComputeIfAbsent(1, func(_ int) *int { panic("mapping panic") })
+	})
+	assert.Equal(t, *m.ComputeIfAbsent(1, func(_ int) *int { return ptr.Of(1) }), 1)
+}
+
+func TestConcurrentMap_SizeConsistency(t *testing.T) {
+	t.Parallel()
+
+	m := NewConcurrentMap[int, int]()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := (i*1000 + j) % 512
+				switch j % 5 {
+				case 0:
+					m.Put(key, ptr.Of(j))
+				case 1:
+					m.Remove(key)
+				case 2:
+					m.PutIfAbsent(key, ptr.Of(j))
+				case 3:
+					m.Compute(key, func(_ int, v *int) *int {
+						if v == nil {
+							return ptr.Of(0)
+						}
+						return nil
+					})
+				default:
+					m.ComputeIfAbsent(key, func(k int) *int { return ptr.Of(k) })
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m.Clear()
+			time.Sleep(50 * time.Microsecond)
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, m.Size(), len(m.KeySet()))
+	m.Clear()
+	assert.Equal(t, m.Size(), 0)
+	assert.Equal(t, len(m.KeySet()), 0)
+}
+
+func TestConcurrentMap_ClearLinearizable(t *testing.T) {
+	t.Parallel()
+
+	m := NewConcurrentMap[int, int]()
+	for i := 0; i < 500; i++ {
+		var wg sync.WaitGroup
+		for k := 0; k < 4; k++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 64; j++ {
+					m.Put(k*64+j, ptr.Of(j))
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				m.Clear()
+				runtime.Gosched()
+			}
+		}()
+		wg.Wait()
+		assert.Equal(t, m.Size(), len(m.KeySet()))
+		m.Clear()
+		assert.Equal(t, m.Size(), 0)
+	}
+}
+
+func TestConcurrentMap_SizeExact(t *testing.T) {
+	t.Parallel()
+
+	m := NewConcurrentMap[int, int]()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the map contains at most one mapping at any time
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				m.Put(0, ptr.Of(i))
+				m.Remove(0)
+			}
+		}()
+	}
+	for i := 0; i < 100000; i++ {
+		if size := m.Size(); size > 1 {
+			t.Fatalf("size is %d", size)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	assert.Equal(t, m.Size(), 0)
+}
+
+func TestConcurrentMap_CallbackReentrant(t *testing.T) {
+	t.Parallel()
+
+	operations := map[string]func(m *ConcurrentMap[int, int], callback func()){
+		"Compute": func(m *ConcurrentMap[int, int], callback func()) {
+			m.Compute(1, func(_ int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+		"ComputeIfPresent": func(m *ConcurrentMap[int, int], callback func()) {
+			m.ComputeIfPresent(1, func(_ int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+		"Merge": func(m *ConcurrentMap[int, int], callback func()) {
+			m.Merge(1, ptr.Of(1), func(_ *int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+		"ReplaceAll": func(m *ConcurrentMap[int, int], callback func()) {
+			m.ReplaceAll(func(_ int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+	}
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			m := NewConcurrentMap[int, int]()
+			m.Put(1, ptr.Of(0))
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				var once sync.Once
+				operation(m, func() {
+					once.Do(func() {
+						// update the map while a Clear is waiting
+						cleared := make(chan struct{})
+						go func() {
+							m.Clear()
+							close(cleared)
+						}()
+						time.Sleep(10 * time.Millisecond)
+						m.Put(2, ptr.Of(2))
+						<-cleared
+					})
+				})
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("deadlock")
+			}
+		})
+	}
+}
+
+func TestMap_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			snapshot := tt.m.Snapshot()
+			tt.m.Put(4, ptr.Of("d"))
+			tt.m.Remove(1)
+
+			assert.Equal(t, snapshot.Size(), 3)
+			assert.Equal(t, snapshot.IsEmpty(), false)
+			assert.Equal(t, snapshot.Get(1), ptr.Of("a"))
+			assert.IsNil(t, snapshot.Get(4))
+			assert.Equal(t, snapshot.ContainsKey(3), true)
+			assert.Equal(t, snapshot.ContainsKey(4), false)
+			assert.ElementsMatch(t, snapshot.KeySet(), []int{1, 2, 3})
+			assert.ElementsMatch(t, snapshot.Values(), []*string{ptr.Of("a"), ptr.Of("b"), ptr.Of("c")})
+			assert.Equal(t, maps.Collect(snapshot.All()),
+				map[int]*string{1: ptr.Of("a"), 2: ptr.Of("b"), 3: ptr.Of("c")})
+
+			tt.m.Clear()
+			assert.Equal(t, tt.m.Snapshot().IsEmpty(), true)
+		})
+	}
+}
+
+func TestMap_PutAll(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.m.PutAll(maps.All(map[int]*string{3: ptr.Of("e"), 4: ptr.Of("d")}))
+			assert.Equal(t, tt.m.Size(), 4)
+			assert.Equal(t, tt.m.Get(3), ptr.Of("e"))
+			assert.Equal(t, tt.m.Get(4), ptr.Of("d"))
+
+			// the sequence may be backed by the map itself
+			other := newTestCounterMap(tt.m)
+			other.Put(1, ptr.Of(1))
+			other.PutAll(other.All())
+			assert.Equal(t, other.Size(), 1)
+		})
+	}
+}
+
+func TestMap_RemoveAll(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.m.RemoveAll(slices.Values([]int{1, 3, 5})), 2)
+			assert.Equal(t, tt.m.Size(), 1)
+			assert.ElementsMatch(t, tt.m.KeySet(), []int{2})
+			assert.Equal(t, tt.m.RemoveAll(slices.Values([]int{})), 0)
+		})
+	}
+}
+
+func TestMap_RemoveIf(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			removed := tt.m.RemoveIf(func(key int, value *string) bool {
+				return key == 1 || *value == "c"
+			})
+			assert.Equal(t, removed, 2)
+			assert.ElementsMatch(t, tt.m.KeySet(), []int{2})
+			assert.Equal(t, tt.m.RemoveIf(func(_ int, _ *string) bool { return false }), 0)
+			assert.Equal(t, tt.m.Size(), 1)
+		})
+	}
+}
+
+func TestMap_ReplaceAll(t *testing.T) {
+	t.Parallel()
+
+	tests := prepareTestMaps()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.m.ReplaceAll(func(key int, value *string) *string {
+				if key == 2 {
+					return nil
+				}
+				return ptr.Of(*value + strconv.Itoa(key))
+			})
+			assert.Equal(t, tt.m.Size(), 2)
+			assert.Equal(t, tt.m.Get(1), ptr.Of("a1"))
+			assert.IsNil(t, tt.m.Get(2))
+			assert.Equal(t, tt.m.Get(3), ptr.Of("c3"))
+		})
+	}
+}
+
+func TestMap_SnapshotConsistent(t *testing.T) {
+	t.Parallel()
+
+	implementations := []Map[int, int]{
+		NewSynchronizedMap[int, int](),
+		NewShardedMap[int, int](8),
+		NewOrderedMap[int, int](),
+	}
+	for _, m := range implementations {
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// all of the keys are updated in one step
+				value := ptr.Of(i)
+				m.PutAll(func(yield func(int, *int) bool) {
+					for key := 0; key < 16; key++ {
+						if !yield(key, value) {
+							return
+						}
+					}
+				})
+				if i%10 == 0 {
+					m.RemoveAll(slices.Values([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}))
+				}
+			}
+		}()
+		for i := 0; i < 200; i++ {
+			snapshot := m.Snapshot()
+			if snapshot.Size() != 0 && snapshot.Size() != 16 {
+				t.Fatalf("inconsistent snapshot size: %d", snapshot.Size())
+			}
+			for _, value := range snapshot.All() {
+				if value != snapshot.Get(0) {
+					t.Fatalf("inconsistent snapshot values: %d, %d", *value, *snapshot.Get(0))
+				}
+			}
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestMap_SnapshotWindow(t *testing.T) {
+	t.Parallel()
+
+	const window = 8
+	implementations := []Map[int, int]{
+		NewConcurrentMap[int, int](),
+		NewLockFreeMap[int, int](),
+		NewObservableMap[int, int](NewConcurrentMap[int, int]()),
+	}
+	for _, m := range implementations {
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the keys of the map are always a contiguous range
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				m.Put(i, ptr.Of(i))
+				if i >= window {
+					m.Remove(i - window)
+				}
+			}
+		}()
+		for i := 0; i < 1000; i++ {
+			keys := m.Snapshot().KeySet()
+			if len(keys) == 0 {
+				continue
+			}
+			if len(keys) > window+1 || slices.Max(keys)-slices.Min(keys) != len(keys)-1 {
+				slices.Sort(keys)
+				t.Fatalf("%T: inconsistent snapshot keys: %v", m, keys)
+			}
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestShardedMap_Grow(t *testing.T) {
+	t.Parallel()
+
+	m := NewShardedMap[int, int](3)
+	for i := 0; i < 1000; i++ {
+		m.Put(i, ptr.Of(i))
+	}
+	m.Grow()
+	assert.Equal(t, m.Shards(), 6)
+	// a single operation migrates only the shard of the key
+	assert.Equal(t, *m.Get(1), 1)
+	assert.NotEqual(t, m.layout.Load().prev.Load(), nil)
+
+	m.Grow()
+	assert.Equal(t, m.Shards(), 12)
+	assert.Equal(t, m.Size(), 1000)
+	for i := 0; i < 1000; i++ {
+		value := m.Get(i)
+		if value == nil || *value != i {
+			t.Fatalf("unexpected value for key %d: %v", i, value)
+		}
+	}
+	assert.Equal(t, m.layout.Load().prev.Load(), nil)
+
+	stats := m.Stats()
+	assert.Equal(t, len(stats), 12)
+	var size int
+	for _, shard := range stats {
+		size += shard.Size
+	}
+	assert.Equal(t, size, 1000)
+}
+
+func TestShardedMap_GrowConcurrent(t *testing.T) {
+	t.Parallel()
+
+	m := NewShardedMap[int, int](1)
+	var wg sync.WaitGroup
+	const writers, keys = 4, 1000
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < keys; j++ {
+				m.Put(i*keys+j, ptr.Of(j))
+				m.Merge(-1, ptr.Of(1), func(a, b *int) *int { return ptr.Of(*a + *b) })
+			}
+		}()
+	}
+	growing := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(growing)
+		for i := 0; i < 5; i++ {
+			m.Grow()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	// the mappings are only added, so the size never decreases
+	for size := 0; ; {
+		select {
+		case <-growing:
+		default:
+			current := m.Size()
+			if current < size {
+				t.Fatalf("size decreased during growth: %d -> %d", size, current)
+			}
+			size = current
+			continue
+		}
+		break
+	}
+	wg.Wait()
+
+	assert.Equal(t, m.Shards(), 32)
+	assert.Equal(t, m.Size(), writers*keys+1)
+	assert.Equal(t, *m.Get(-1), writers*keys)
+	for i := 0; i < writers*keys; i++ {
+		if value := m.Get(i); value == nil || *value != i%keys {
+			t.Fatalf("unexpected value for key %d: %v", i, value)
+		}
+	}
+}
+
+func TestShardedMap_GrowConcurrentSize(t *testing.T) {
+	t.Parallel()
+
+	const size = 5000
+	m := NewShardedMap[int, int](1)
+	for i := 0; i < size; i++ {
+		m.Put(i, ptr.Of(i))
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			m.Grow()
+			// migrate some of the shards to the new layout
+			for j := 0; j < size; j += 7 {
+				_ = m.Get(j)
+			}
+		}
+	}()
+	// the mappings are unchanged while the shards are moved
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if n := m.Size(); n != size {
+			t.Fatalf("unexpected size during growth: %d", n)
+		}
+		if n := len(m.KeySet()); n != size {
+			t.Fatalf("unexpected number of keys during growth: %d", n)
+		}
+		assert.Equal(t, m.IsEmpty(), false)
+	}
+	wg.Wait()
+	assert.Equal(t, m.Shards(), 1024)
+	var total int
+	for _, stats := range m.Stats() {
+		total += stats.Size
+	}
+	assert.Equal(t, total, size)
+}
+
+func TestShardedMap_MaxShardSize(t *testing.T) {
+	t.Parallel()
+
+	m := NewShardedMapWithHash[int, int](2, func(k int) uint64 { return uint64(k) })
+	m.SetMaxShardSize(8)
+	for i := 0; i < 64; i++ {
+		m.Put(i, ptr.Of(i))
+	}
+	assert.Equal(t, m.Shards(), 8)
+	for _, shard := range m.Stats() {
+		assert.Equal(t, shard.Size, 8)
+		assert.Equal(t, shard.Operations > 0, true)
+	}
+
+	m.SetMaxShardSize(0)
+	for i := 64; i < 128; i++ {
+		m.Put(i, ptr.Of(i))
+	}
+	assert.Equal(t, m.Shards(), 8)
+	assert.Equal(t, m.Size(), 128)
+}
+
+func TestOrderedMap_Navigation(t *testing.T) {
+	t.Parallel()
+
+	m := NewOrderedMap[int, int]()
+	_, _, ok := m.First()
+	assert.Equal(t, ok, false)
+	_, _, ok = m.Last()
+	assert.Equal(t, ok, false)
+
+	for _, key := range []int{50, 10, 40, 20, 30} {
+		m.Put(key, ptr.Of(key*10))
+	}
+	assertEntry := func(key int, value *int, ok bool, expected int) {
+		t.Helper()
+		assert.Equal(t, ok, true)
+		assert.Equal(t, key, expected)
+		assert.Equal(t, *value, expected*10)
+	}
+	key, value, ok := m.First()
+	assertEntry(key, value, ok, 10)
+	key, value, ok = m.Last()
+	assertEntry(key, value, ok, 50)
+	key, value, ok = m.Floor(35)
+	assertEntry(key, value, ok, 30)
+	key, value, ok = m.Floor(30)
+	assertEntry(key, value, ok, 30)
+	key, value, ok = m.Ceiling(35)
+	assertEntry(key, value, ok, 40)
+	key, value, ok = m.Ceiling(40)
+	assertEntry(key, value, ok, 40)
+	_, _, ok = m.Floor(5)
+	assert.Equal(t, ok, false)
+	_, _, ok = m.Ceiling(55)
+	assert.Equal(t, ok, false)
+
+	assert.Equal(t, m.KeySet(), []int{10, 20, 30, 40, 50})
+	m.Remove(50)
+	key, value, ok = m.Last()
+	assertEntry(key, value, ok, 40)
+}
+
+func TestOrderedMap_Iteration(t *testing.T) {
+	t.Parallel()
+
+	m := NewOrderedMap[int, int]()
+	for i := 9; i >= 0; i-- {
+		m.Put(i, ptr.Of(i))
+	}
+	collect := func(seq iter.Seq2[int, *int]) []int {
+		var keys []int
+		for key, value := range seq {
+			assert.Equal(t, *value, key)
+			keys = append(keys, key)
+		}
+		return keys
+	}
+	assert.Equal(t, collect(m.All()), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	assert.Equal(t, collect(m.Descending()), []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0})
+	assert.Equal(t, collect(m.Range(3, 7)), []int{3, 4, 5, 6})
+	assert.Equal(t, collect(m.Range(-5, 2)), []int{0, 1})
+	assert.IsNil(t, collect(m.Range(7, 3)))
+
+	// the map can be modified during the iteration
+	var keys []int
+	for key := range m.All() {
+		keys = append(keys, key)
+		m.Remove(key)
+		m.Remove(key + 1)
+		if key == 4 {
+			m.Put(100, ptr.Of(100))
+		}
+	}
+	assert.Equal(t, keys, []int{0, 2, 4, 6, 8, 100})
+	assert.Equal(t, m.Size(), 0)
+
+	m.Clear()
+	for key := range m.Descending() {
+		t.Fatalf("unexpected key: %d", key)
+	}
+}
+
+func TestOrderedMap_Random(t *testing.T) {
+	t.Parallel()
+
+	m := NewOrderedMap[int, int]()
+	reference := make(map[int]int)
+	for i := 0; i < 5000; i++ {
+		key := (i * 7919) % 1000
+		if i%3 == 0 {
+			m.Remove(key)
+			delete(reference, key)
+		} else {
+			m.Put(key, ptr.Of(i))
+			reference[key] = i
+		}
+	}
+	keys := slices.Sorted(maps.Keys(reference))
+	assert.Equal(t, m.KeySet(), keys)
+	assert.Equal(t, m.Size(), len(reference))
+	descending := slices.Collect(func(yield func(int) bool) {
+		for key := range m.Descending() {
+			if !yield(key) {
+				return
+			}
+		}
+	})
+	slices.Reverse(keys)
+	assert.Equal(t, descending, keys)
+}
+
+func TestLockFreeMap_ConstructorArguments(t *testing.T) {
+	t.Parallel()
+
+	assert.PanicMsgContains(t, func() {
+		NewLockFreeMapWithHash[int, string](nil)
+	}, "hashFunc is nil")
+
+	NewLockFreeMapWithHash[int, string](func(_ int) uint64 { return 1 })
+}
+
+func TestLockFreeMap_Resize(t *testing.T) {
+	t.Parallel()
+
+	m := NewLockFreeMapWithHash[int, int](func(k int) uint64 { return uint64(k) })
+	const n = 10000
+	for i := 0; i < n; i++ {
+		m.Put(i, ptr.Of(i))
+	}
+	assert.Equal(t, m.Size(), n)
+	assert.Equal(t, len(m.table.Load().buckets) > lockFreeInitialBuckets, true)
+	for i := 0; i < n; i++ {
+		value := m.Get(i)
+		if value == nil || *value != i {
+			t.Fatalf("unexpected value for key %d: %v", i, value)
+		}
+	}
+	for i := 0; i < n; i += 2 {
+		assert.Equal(t, *m.Remove(i), i)
+	}
+	assert.Equal(t, m.Size(), n/2)
+	assert.Equal(t, len(m.KeySet()), n/2)
+
+	m.Clear()
+	assert.Equal(t, m.Size(), 0)
+	assert.Equal(t, len(m.table.Load().buckets), lockFreeInitialBuckets)
+	assert.IsNil(t, m.Get(1))
+}
+
+func TestLockFreeMap_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	m := NewLockFreeMap[int, int]()
+	var wg sync.WaitGroup
+	const writers, keys = 4, 2000
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < keys; j++ {
+				m.Put(i*keys+j, ptr.Of(j))
+				m.Merge(-1, ptr.Of(1), func(a, b *int) *int { return ptr.Of(*a + *b) })
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < keys; j++ {
+				if value := m.Get(i*keys + j); value != nil && *value != j {
+					t.Errorf("unexpected value: %d", *value)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, m.Size(), writers*keys+1)
+	assert.Equal(t, *m.Get(-1), writers*keys)
+	seen := make(map[int]struct{})
+	for key := range m.All() {
+		if _, ok := seen[key]; ok {
+			t.Fatalf("duplicate key: %d", key)
+		}
+		seen[key] = struct{}{}
+	}
+	assert.Equal(t, len(seen), writers*keys+1)
+}
+
+func TestLockFreeMap_SizeConsistency(t *testing.T) {
+	t.Parallel()
+
+	m := NewLockFreeMap[int, int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5000; j++ {
+				key := (i*1000 + j) % 2048
+				switch j % 4 {
+				case 0:
+					m.Put(key, ptr.Of(j))
+				case 1:
+					m.Remove(key)
+				case 2:
+					m.PutIfAbsent(key, ptr.Of(j))
+				default:
+					if j%100 == 3 {
+						m.Clear()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, m.Size(), len(m.KeySet()))
+	m.Clear()
+	assert.Equal(t, m.Size(), 0)
+	assert.Equal(t, len(m.KeySet()), 0)
+}
+
+func TestObservableMap_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	m := NewObservableMap[int, string](NewSynchronizedMap[int, string]())
+	events := make(chan MapEvent[int, string], 16)
+	unsubscribe := m.Subscribe(func(event MapEvent[int, string]) {
+		events <- event
+	})
+
+	a, b := ptr.Of("a"), ptr.Of("b")
+	m.Put(1, a)
+	m.Put(1, b)
+	m.PutIfAbsent(1, a)
+	m.Compute(2, func(_ int, _ *string) *string { return a })
+	m.ComputeIfPresent(2, func(_ int, _ *string) *string { return nil })
+	m.Remove(3)
+	m.Remove(1)
+	m.Clear()
+
+	expected := []MapEvent[int, string]{
+		{Type: MapEventPut, Key: 1, NewValue: a},
+		{Type: MapEventPut, Key: 1, OldValue: a, NewValue: b},
+		{Type: MapEventPut, Key: 2, NewValue: a},
+		{Type: MapEventRemove, Key: 2, OldValue: a},
+		{Type: MapEventRemove, Key: 1, OldValue: b},
+		{Type: MapEventClear},
+	}
+	for _, event := range expected {
+		assert.Equal(t, receiveEvent(t, events), event)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	m.Put(1, a)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, len(events), 0)
+}
+
+func TestObservableMap_BulkEvents(t *testing.T) {
+	t.Parallel()
+
+	m := NewObservableMap[int, string](NewShardedMap[int, string](2))
+	putValues(m)
+	events := m.Events(context.Background())
+
+	m.PutAll(maps.All(map[int]*string{4: ptr.Of("d")}))
+	assert.Equal(t, receiveEvent(t, events), MapEvent[int, string]{
+		Type: MapEventPut, Key: 4, NewValue: ptr.Of("d")})
+
+	removed := m.RemoveAll(slices.Values([]int{4, 5}))
+	assert.Equal(t, removed, 1)
+	assert.Equal(t, receiveEvent(t, events), MapEvent[int, string]{
+		Type: MapEventRemove, Key: 4, OldValue: ptr.Of("d")})
+
+	m.RemoveIf(func(key int, _ *string) bool { return key == 3 })
+	assert.Equal(t, receiveEvent(t, events), MapEvent[int, string]{
+		Type: MapEventRemove, Key: 3, OldValue: ptr.Of("c")})
+
+	m.ReplaceAll(func(key int, value *string) *string {
+		if key == 1 {
+			return nil
+		}
+		return ptr.Of(*value + "2")
+	})
+	received := []MapEvent[int, string]{receiveEvent(t, events), receiveEvent(t, events)}
+	slices.SortFunc(received, func(a, b MapEvent[int, string]) int { return a.Key - b.Key })
+	assert.Equal(t, received, []MapEvent[int, string]{
+		{Type: MapEventRemove, Key: 1, OldValue: ptr.Of("a")},
+		{Type: MapEventPut, Key: 2, OldValue: ptr.Of("b"), NewValue: ptr.Of("b2")},
+	})
+}
+
+func TestObservableMap_Watch(t *testing.T) {
+	t.Parallel()
+
+	m := NewObservableMap[int, string](NewConcurrentMap[int, string]())
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := m.Watch(ctx, 1)
+
+	m.Put(2, ptr.Of("b"))
+	m.Put(1, ptr.Of("a"))
+	m.Merge(1, ptr.Of("b"), func(oldValue, value *string) *string {
+		return ptr.Of(*oldValue + *value)
+	})
+	m.Remove(2)
+	m.Clear()
+
+	assert.Equal(t, receiveEvent(t, updates), MapEvent[int, string]{
+		Type: MapEventPut, Key: 1, NewValue: ptr.Of("a")})
+	assert.Equal(t, receiveEvent(t, updates), MapEvent[int, string]{
+		Type: MapEventPut, Key: 1, OldValue: ptr.Of("a"), NewValue: ptr.Of("ab")})
+	assert.Equal(t, receiveEvent(t, updates), MapEvent[int, string]{Type: MapEventClear})
+
+	// the channel is closed even if the pending events are not received
+	m.Put(1, ptr.Of("c"))
+	cancel()
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("watch channel is not closed")
+	}
+	for range updates {
+	}
+	m.Put(1, ptr.Of("d"))
+}
+
+func TestObservableMap_EventOrder(t *testing.T) {
+	t.Parallel()
+
+	m := NewObservableMap[int, int](NewShardedMap[int, int](4))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := m.Watch(ctx, 0)
+
+	const writers, increments = 4, 250
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				m.Merge(0, ptr.Of(1), func(oldValue, value *int) *int {
+					return ptr.Of(*oldValue + *value)
+				})
+				m.Put(i+1, ptr.Of(j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 1; i <= writers*increments; i++ {
+		event := receiveEvent(t, updates)
+		assert.Equal(t, *event.NewValue, i)
+	}
+}
+
+func TestObservableMap_CallbackReentrant(t *testing.T) {
+	t.Parallel()
+
+	operations := map[string]func(m *ObservableMap[int, int], callback func()){
+		"Compute": func(m *ObservableMap[int, int], callback func()) {
+			m.Compute(1, func(_ int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+		"ComputeIfAbsent": func(m *ObservableMap[int, int], callback func()) {
+			m.ComputeIfAbsent(0, func(_ int) *int { callback(); return ptr.Of(1) })
+		},
+		"ComputeIfPresent": func(m *ObservableMap[int, int], callback func()) {
+			m.ComputeIfPresent(1, func(_ int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+		"Merge": func(m *ObservableMap[int, int], callback func()) {
+			m.Merge(1, ptr.Of(1), func(_ *int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+		"RemoveIf": func(m *ObservableMap[int, int], callback func()) {
+			m.RemoveIf(func(_ int, _ *int) bool { callback(); return true })
+		},
+		"ReplaceAll": func(m *ObservableMap[int, int], callback func()) {
+			m.ReplaceAll(func(_ int, _ *int) *int { callback(); return ptr.Of(1) })
+		},
+	}
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			m := NewObservableMap[int, int](NewConcurrentMap[int, int]())
+			m.Put(1, ptr.Of(0))
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				var once sync.Once
+				operation(m, func() {
+					once.Do(func() {
+						// access keys of every stripe
+						_ = m.Get(1)
+						for key := 1000; key < 2000; key++ {
+							m.Put(key, ptr.Of(key))
+						}
+					})
+				})
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("deadlock")
+			}
+		})
+	}
+}
+
+func receiveEvent[K comparable, V any](t *testing.T, events <-chan MapEvent[K, V]) MapEvent[K, V] {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("events channel is closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return MapEvent[K, V]{}
+}
+
 func prepareTestMaps() []testMap {
-	tests := make([]testMap, 0, 2)
+	tests := make([]testMap, 0, 6)
 	concurrentMap := NewConcurrentMap[int, string]()
 	putValues(concurrentMap)
 	tests = append(tests, testMap{"concurrentMap", concurrentMap})
 	shardedMap := NewShardedMap[int, string](2)
 	putValues(shardedMap)
 	tests = append(tests, testMap{"shardedMap", shardedMap})
+	synchronizedMap := NewSynchronizedMap[int, string]()
+	putValues(synchronizedMap)
+	tests = append(tests, testMap{"synchronizedMap", synchronizedMap})
+	lockFreeMap := NewLockFreeMap[int, string]()
+	putValues(lockFreeMap)
+	tests = append(tests, testMap{"lockFreeMap", lockFreeMap})
+	orderedMap := NewOrderedMap[int, string]()
+	putValues(orderedMap)
+	tests = append(tests, testMap{"orderedMap", orderedMap})
+	observableMap := NewObservableMap[int, string](NewConcurrentMap[int, string]())
+	putValues(observableMap)
+	tests = append(tests, testMap{"observableMap", observableMap})
 	return tests
 }
 
+// newTestCounterMap returns a new empty map of the same implementation
+// as the given map.
+func newTestCounterMap(m Map[int, string]) Map[int, int] {
+	switch m.(type) {
+	case *ConcurrentMap[int, string]:
+		return NewConcurrentMap[int, int]()
+	case *ShardedMap[int, string]:
+		return NewShardedMap[int, int](2)
+	case *LockFreeMap[int, string]:
+		return NewLockFreeMap[int, int]()
+	case *OrderedMap[int, string]:
+		return NewOrderedMap[int, int]()
+	case *ObservableMap[int, string]:
+		return NewObservableMap[int, int](NewConcurrentMap[int, int]())
+	default:
+		return NewSynchronizedMap[int, int]()
+	}
+}
+
 func putValues(m Map[int, string]) {
 	m.Put(1, ptr.Of("a"))
 	m.Put(2, ptr.Of("b"))
@@ -0,0 +1,367 @@
+package async
+
+import (
+	"fmt"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// A ValueMap is an object that maps keys to values, storing the values
+// directly rather than by pointer. Unlike Map, storing a value does not
+// require a heap allocation per value, and a missing mapping is reported
+// explicitly, so the zero value can be stored as any other value.
+type ValueMap[K comparable, V any] interface {
+
+	// Clear removes all of the mappings from this map.
+	Clear()
+
+	// Delete removes the mapping for the specified key from this map.
+	Delete(key K)
+
+	// Load returns the value to which the specified key is mapped and true,
+	// or the zero value and false if this map contains no mapping for the key.
+	Load(key K) (V, bool)
+
+	// LoadAndDelete removes the mapping for the specified key, returning the
+	// previous value if any. The loaded result reports whether the key
+	// was present.
+	LoadAndDelete(key K) (value V, loaded bool)
+
+	// LoadOrStore returns the existing value for the specified key if present.
+	// Otherwise, it stores and returns the given value. The loaded result
+	// is true if the value was loaded, false if stored.
+	LoadOrStore(key K, value V) (actual V, loaded bool)
+
+	// Size returns the number of key-value mappings in this map.
+	Size() int
+
+	// Store associates the specified value with the specified key in this map.
+	Store(key K, value V)
+
+	// All returns an iterator of all key-value pairs in this map.
+	// The order of the pairs is not specified.
+	All() iter.Seq2[K, V]
+}
+
+// ConcurrentValueMap implements the async.ValueMap interface in a thread-safe
+// manner by delegating load/store operations to the underlying sync.Map.
+// A ConcurrentValueMap must not be copied.
+//
+// The values are stored in the sync.Map as interface values, so storing
+// a value that does not fit into a pointer word may still allocate.
+// Loading a value never allocates.
+type ConcurrentValueMap[K comparable, V any] struct {
+	mtx  sync.RWMutex
+	m    sync.Map
+	size atomic.Int64
+}
+
+var _ ValueMap[int, any] = (*ConcurrentValueMap[int, any])(nil)
+
+// NewConcurrentValueMap returns a new ConcurrentValueMap instance.
+func NewConcurrentValueMap[K comparable, V any]() *ConcurrentValueMap[K, V] {
+	return &ConcurrentValueMap[K, V]{}
+}
+
+// Clear removes all of the mappings from this map.
+func (cm *ConcurrentValueMap[K, V]) Clear() {
+	cm.mtx.Lock()
+	defer cm.mtx.Unlock()
+	cm.m.Clear()
+	cm.size.Store(0)
+}
+
+// Delete removes the mapping for the specified key from this map.
+func (cm *ConcurrentValueMap[K, V]) Delete(key K) {
+	_, _ = cm.LoadAndDelete(key)
+}
+
+// Load returns the value to which the specified key is mapped and true,
+// or the zero value and false if this map contains no mapping for the key.
+func (cm *ConcurrentValueMap[K, V]) Load(key K) (V, bool) {
+	value, ok := cm.m.Load(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	// a nil interface value is stored as a nil any
+	v, _ := value.(V)
+	return v, true
+}
+
+// LoadAndDelete removes the mapping for the specified key, returning the
+// previous value if any. The loaded result reports whether the key
+// was present.
+func (cm *ConcurrentValueMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	previous, loaded := cm.m.LoadAndDelete(key)
+	if !loaded {
+		return value, false
+	}
+	cm.size.Add(-1)
+	value, _ = previous.(V)
+	return value, true
+}
+
+// LoadOrStore returns the existing value for the specified key if present.
+// Otherwise, it stores and returns the given value. The loaded result
+// is true if the value was loaded, false if stored.
+func (cm *ConcurrentValueMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	if current, ok := cm.m.Load(key); ok {
+		actual, _ = current.(V)
+		return actual, true
+	}
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	current, loaded := cm.m.LoadOrStore(key, value)
+	if !loaded {
+		cm.size.Add(1)
+	}
+	actual, _ = current.(V)
+	return actual, loaded
+}
+
+// Size returns the number of key-value mappings in this map.
+func (cm *ConcurrentValueMap[K, V]) Size() int {
+	return int(cm.size.Load())
+}
+
+// Store associates the specified value with the specified key in this map.
+func (cm *ConcurrentValueMap[K, V]) Store(key K, value V) {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+	if _, loaded := cm.m.Swap(key, value); !loaded {
+		cm.size.Add(1)
+	}
+}
+
+// All returns an iterator of all key-value pairs in this map.
+// The order of the pairs is not specified.
+func (cm *ConcurrentValueMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		cm.m.Range(func(key, value any) bool {
+			v, _ := value.(V)
+			return yield(key.(K), v)
+		})
+	}
+}
+
+// ShardedValueMap implements the async.ValueMap interface in a thread-safe
+// manner, delegating load/store operations to one of the underlying
+// async.SynchronizedValueMaps (shards), using a key hash to calculate
+// the shard number.
+type ShardedValueMap[K comparable, V any] struct {
+	shards   []*SynchronizedValueMap[K, V]
+	hashFunc func(K) uint64
+}
+
+var _ ValueMap[int, any] = (*ShardedValueMap[int, any])(nil)
+
+// NewShardedValueMap returns a new ShardedValueMap, where shards is the number
+// of partitions for this map. It uses a hash/maphash based function with a
+// random per-map seed to calculate the shard number for a key.
+// If the shards argument is not positive, NewShardedValueMap will panic.
+func NewShardedValueMap[K comparable, V any](shards int) *ShardedValueMap[K, V] {
+	return NewShardedValueMapWithHash[K, V](shards, newHashFunc[K]())
+}
+
+// NewShardedValueMapWithHash returns a new ShardedValueMap, where shards is the
+// number of partitions for this map, and hashFunc is a hash function to calculate
+// the shard number for a key.
+// If shards is not positive or hashFunc is nil, NewShardedValueMapWithHash will panic.
+func NewShardedValueMapWithHash[K comparable, V any](shards int,
+	hashFunc func(K) uint64) *ShardedValueMap[K, V] {
+	if shards < 1 {
+		panic(fmt.Sprintf("nonpositive shards: %d", shards))
+	}
+	if hashFunc == nil {
+		panic("hashFunc is nil")
+	}
+	sm := &ShardedValueMap[K, V]{
+		shards:   make([]*SynchronizedValueMap[K, V], shards),
+		hashFunc: hashFunc,
+	}
+	for i := range sm.shards {
+		sm.shards[i] = NewSynchronizedValueMap[K, V]()
+	}
+	return sm
+}
+
+// Clear removes all of the mappings from this map.
+func (sm *ShardedValueMap[K, V]) Clear() {
+	for _, shard := range sm.shards {
+		shard.Clear()
+	}
+}
+
+// Delete removes the mapping for the specified key from this map.
+func (sm *ShardedValueMap[K, V]) Delete(key K) {
+	sm.shard(key).Delete(key)
+}
+
+// Load returns the value to which the specified key is mapped and true,
+// or the zero value and false if this map contains no mapping for the key.
+func (sm *ShardedValueMap[K, V]) Load(key K) (V, bool) {
+	return sm.shard(key).Load(key)
+}
+
+// LoadAndDelete removes the mapping for the specified key, returning the
+// previous value if any. The loaded result reports whether the key
+// was present.
+func (sm *ShardedValueMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	return sm.shard(key).LoadAndDelete(key)
+}
+
+// LoadOrStore returns the existing value for the specified key if present.
+// Otherwise, it stores and returns the given value. The loaded result
+// is true if the value was loaded, false if stored.
+func (sm *ShardedValueMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return sm.shard(key).LoadOrStore(key, value)
+}
+
+// Size returns the number of key-value mappings in this map.
+func (sm *ShardedValueMap[K, V]) Size() int {
+	var size int
+	for _, shard := range sm.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+// Store associates the specified value with the specified key in this map.
+func (sm *ShardedValueMap[K, V]) Store(key K, value V) {
+	sm.shard(key).Store(key, value)
+}
+
+// All returns an iterator of all key-value pairs in this map.
+// The order of the pairs is not specified.
+// The shards are copied one at a time and the pairs are yielded with no lock
+// held, so the map may be modified during the iteration.
+func (sm *ShardedValueMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, shard := range sm.shards {
+			for _, entry := range shard.entries() {
+				if !yield(entry.key, entry.value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (sm *ShardedValueMap[K, V]) shard(key K) *SynchronizedValueMap[K, V] {
+	return sm.shards[sm.hashFunc(key)%uint64(len(sm.shards))]
+}
+
+// SynchronizedValueMap implements the async.ValueMap interface in a thread-safe
+// manner, delegating load/store operations to a Go map and using a sync.RWMutex
+// for synchronization.
+type SynchronizedValueMap[K comparable, V any] struct {
+	sync.RWMutex
+	store map[K]V
+}
+
+var _ ValueMap[int, any] = (*SynchronizedValueMap[int, any])(nil)
+
+// NewSynchronizedValueMap returns a new SynchronizedValueMap.
+func NewSynchronizedValueMap[K comparable, V any]() *SynchronizedValueMap[K, V] {
+	return &SynchronizedValueMap[K, V]{
+		store: make(map[K]V),
+	}
+}
+
+// Clear removes all of the mappings from this map.
+func (sync *SynchronizedValueMap[K, V]) Clear() {
+	sync.Lock()
+	defer sync.Unlock()
+	clear(sync.store)
+}
+
+// Delete removes the mapping for the specified key from this map.
+func (sync *SynchronizedValueMap[K, V]) Delete(key K) {
+	sync.Lock()
+	defer sync.Unlock()
+	delete(sync.store, key)
+}
+
+// Load returns the value to which the specified key is mapped and true,
+// or the zero value and false if this map contains no mapping for the key.
+func (sync *SynchronizedValueMap[K, V]) Load(key K) (V, bool) {
+	sync.RLock()
+	defer sync.RUnlock()
+	value, ok := sync.store[key]
+	return value, ok
+}
+
+// LoadAndDelete removes the mapping for the specified key, returning the
+// previous value if any. The loaded result reports whether the key
+// was present.
+func (sync *SynchronizedValueMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	sync.Lock()
+	defer sync.Unlock()
+	value, loaded = sync.store[key]
+	if loaded {
+		delete(sync.store, key)
+	}
+	return value, loaded
+}
+
+// LoadOrStore returns the existing value for the specified key if present.
+// Otherwise, it stores and returns the given value. The loaded result
+// is true if the value was loaded, false if stored.
+func (sync *SynchronizedValueMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	sync.Lock()
+	defer sync.Unlock()
+	if current, ok := sync.store[key]; ok {
+		return current, true
+	}
+	sync.store[key] = value
+	return value, false
+}
+
+// Size returns the number of key-value mappings in this map.
+func (sync *SynchronizedValueMap[K, V]) Size() int {
+	sync.RLock()
+	defer sync.RUnlock()
+	return len(sync.store)
+}
+
+// Store associates the specified value with the specified key in this map.
+func (sync *SynchronizedValueMap[K, V]) Store(key K, value V) {
+	sync.Lock()
+	defer sync.Unlock()
+	sync.store[key] = value
+}
+
+// All returns an iterator of all key-value pairs in this map.
+// The order of the pairs is not specified.
+// The map is read-locked for the duration of the iteration.
+func (sync *SynchronizedValueMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sync.RLock()
+		defer sync.RUnlock()
+		for key, value := range sync.store {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+// entries returns a copy of the mappings in this map.
+func (sync *SynchronizedValueMap[K, V]) entries() []valueEntry[K, V] {
+	sync.RLock()
+	defer sync.RUnlock()
+	entries := make([]valueEntry[K, V], 0, len(sync.store))
+	for key, value := range sync.store {
+		entries = append(entries, valueEntry[K, V]{key, value})
+	}
+	return entries
+}
+
+type valueEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
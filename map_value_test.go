@@ -0,0 +1,202 @@
+package async
+
+import (
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestValueMap_LoadStore(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestValueMaps() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := tt.m
+
+			value, ok := m.Load(1)
+			assert.Equal(t, value, 0)
+			assert.Equal(t, ok, false)
+
+			m.Store(1, 0)
+			value, ok = m.Load(1)
+			assert.Equal(t, value, 0)
+			assert.Equal(t, ok, true)
+
+			m.Store(1, 10)
+			m.Store(2, 20)
+			value, ok = m.Load(1)
+			assert.Equal(t, value, 10)
+			assert.Equal(t, ok, true)
+			assert.Equal(t, m.Size(), 2)
+			assert.Equal(t, maps.Collect(m.All()), map[int]int{1: 10, 2: 20})
+		})
+	}
+}
+
+func TestValueMap_LoadOrStore(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestValueMaps() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := tt.m
+
+			actual, loaded := m.LoadOrStore(1, 10)
+			assert.Equal(t, actual, 10)
+			assert.Equal(t, loaded, false)
+
+			actual, loaded = m.LoadOrStore(1, 20)
+			assert.Equal(t, actual, 10)
+			assert.Equal(t, loaded, true)
+			assert.Equal(t, m.Size(), 1)
+		})
+	}
+}
+
+func TestValueMap_Delete(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestValueMaps() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := tt.m
+			m.Store(1, 10)
+			m.Store(2, 20)
+			m.Store(3, 30)
+
+			value, loaded := m.LoadAndDelete(1)
+			assert.Equal(t, value, 10)
+			assert.Equal(t, loaded, true)
+
+			value, loaded = m.LoadAndDelete(1)
+			assert.Equal(t, value, 0)
+			assert.Equal(t, loaded, false)
+
+			m.Delete(2)
+			m.Delete(4)
+			assert.Equal(t, m.Size(), 1)
+			assert.Equal(t, maps.Collect(m.All()), map[int]int{3: 30})
+
+			m.Clear()
+			assert.Equal(t, m.Size(), 0)
+			_, ok := m.Load(3)
+			assert.Equal(t, ok, false)
+		})
+	}
+}
+
+func TestValueMap_NilInterface(t *testing.T) {
+	t.Parallel()
+
+	implementations := map[string]ValueMap[string, error]{
+		"concurrentValueMap":   NewConcurrentValueMap[string, error](),
+		"shardedValueMap":      NewShardedValueMap[string, error](4),
+		"synchronizedValueMap": NewSynchronizedValueMap[string, error](),
+	}
+	for name, m := range implementations {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			m.Store("a", nil)
+			value, ok := m.Load("a")
+			assert.IsNil(t, value)
+			assert.Equal(t, ok, true)
+
+			actual, loaded := m.LoadOrStore("a", errors.New("b"))
+			assert.IsNil(t, actual)
+			assert.Equal(t, loaded, true)
+			actual, loaded = m.LoadOrStore("b", nil)
+			assert.IsNil(t, actual)
+			assert.Equal(t, loaded, false)
+
+			assert.Equal(t, maps.Collect(m.All()), map[string]error{"a": nil, "b": nil})
+			value, loaded = m.LoadAndDelete("a")
+			assert.IsNil(t, value)
+			assert.Equal(t, loaded, true)
+			assert.Equal(t, m.Size(), 1)
+		})
+	}
+}
+
+func TestValueMap_ModifyWhileIterating(t *testing.T) {
+	t.Parallel()
+
+	implementations := map[string]ValueMap[int, int]{
+		"concurrentValueMap": NewConcurrentValueMap[int, int](),
+		"shardedValueMap":    NewShardedValueMap[int, int](1),
+	}
+	for name, m := range implementations {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for i := 0; i < 10; i++ {
+				m.Store(i, i)
+			}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for key, value := range m.All() {
+					m.Store(key, value+1)
+					m.Delete(key + 100)
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("deadlock")
+			}
+			assert.Equal(t, m.Size(), 10)
+		})
+	}
+}
+
+func TestValueMap_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestValueMaps() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := tt.m
+
+			var wg sync.WaitGroup
+			var stored sync.Map
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 1000; j++ {
+						key := j % 100
+						if _, loaded := m.LoadOrStore(key, i); !loaded {
+							if _, dup := stored.LoadOrStore(key, i); dup {
+								t.Errorf("value stored twice for key %d", key)
+							}
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, m.Size(), 100)
+			for key, value := range m.All() {
+				expected, _ := stored.Load(key)
+				assert.Equal(t, value, expected.(int))
+			}
+		})
+	}
+}
+
+func prepareTestValueMaps() []testValueMap {
+	return []testValueMap{
+		{"concurrentValueMap", NewConcurrentValueMap[int, int]()},
+		{"shardedValueMap", NewShardedValueMap[int, int](4)},
+		{"synchronizedValueMap", NewSynchronizedValueMap[int, int]()},
+	}
+}
+
+type testValueMap struct {
+	name string
+	m    ValueMap[int, int]
+}
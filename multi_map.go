@@ -0,0 +1,135 @@
+package async
+
+import (
+	"iter"
+	"slices"
+)
+
+// MultiMap is a thread-safe map that associates each key with a list of
+// values. The keys are partitioned using an async.ShardedMap, so that the
+// operations on unrelated keys do not contend.
+//
+// The values of a key are stored in a copy-on-write slice, which is replaced
+// atomically on each update. The slices returned by the MultiMap are shared
+// snapshots, so they must not be modified.
+type MultiMap[K comparable, V comparable] struct {
+	m *ShardedMap[K, []V]
+}
+
+// NewMultiMap returns a new MultiMap, where shards is the number of partitions
+// for this map.
+// If the shards argument is not positive, NewMultiMap will panic.
+func NewMultiMap[K comparable, V comparable](shards int) *MultiMap[K, V] {
+	return &MultiMap[K, V]{
+		m: NewShardedMap[K, []V](shards),
+	}
+}
+
+// Add appends the value to the values associated with the key.
+func (mm *MultiMap[K, V]) Add(key K, value V) {
+	mm.m.Compute(key, func(_ K, values *[]V) *[]V {
+		if values == nil {
+			return &[]V{value}
+		}
+		// the stored slices are clipped, so that append always copies
+		updated := slices.Clip(append(*values, value))
+		return &updated
+	})
+}
+
+// AddAll appends the values to the values associated with the key.
+func (mm *MultiMap[K, V]) AddAll(key K, values ...V) {
+	if len(values) == 0 {
+		return
+	}
+	mm.m.Compute(key, func(_ K, current *[]V) *[]V {
+		var updated []V
+		if current != nil {
+			updated = *current
+		}
+		updated = slices.Clip(append(updated, values...))
+		return &updated
+	})
+}
+
+// Clear removes all of the mappings from this map.
+func (mm *MultiMap[K, V]) Clear() {
+	mm.m.Clear()
+}
+
+// ContainsKey returns true if this map contains at least one value for
+// the specified key.
+func (mm *MultiMap[K, V]) ContainsKey(key K) bool {
+	return mm.m.Get(key) != nil
+}
+
+// ContainsValue returns true if the specified value is associated with
+// the specified key.
+func (mm *MultiMap[K, V]) ContainsValue(key K, value V) bool {
+	return slices.Contains(mm.Get(key), value)
+}
+
+// Count returns the number of values associated with the specified key.
+func (mm *MultiMap[K, V]) Count(key K) int {
+	return len(mm.Get(key))
+}
+
+// Get returns a snapshot of the values associated with the specified key,
+// or nil if there are none. The returned slice must not be modified.
+func (mm *MultiMap[K, V]) Get(key K) []V {
+	if values := mm.m.Get(key); values != nil {
+		return *values
+	}
+	return nil
+}
+
+// Keys returns a slice of the keys contained in this map.
+func (mm *MultiMap[K, V]) Keys() []K {
+	return mm.m.KeySet()
+}
+
+// RemoveAll removes all of the values associated with the specified key,
+// returning the removed values or nil if none.
+func (mm *MultiMap[K, V]) RemoveAll(key K) []V {
+	if values := mm.m.Remove(key); values != nil {
+		return *values
+	}
+	return nil
+}
+
+// RemoveValue removes the first occurrence of the value from the values
+// associated with the key. The key is removed with its last value.
+// It returns true if the value was removed.
+func (mm *MultiMap[K, V]) RemoveValue(key K, value V) bool {
+	var removed bool
+	mm.m.ComputeIfPresent(key, func(_ K, values *[]V) *[]V {
+		i := slices.Index(*values, value)
+		if i < 0 {
+			return values
+		}
+		removed = true
+		if len(*values) == 1 {
+			return nil
+		}
+		updated := slices.Clip(slices.Delete(slices.Clone(*values), i, i+1))
+		return &updated
+	})
+	return removed
+}
+
+// Size returns the number of keys in this map.
+func (mm *MultiMap[K, V]) Size() int {
+	return mm.m.Size()
+}
+
+// All returns an iterator of the keys and snapshots of their values
+// in this map. The order of the keys is not specified.
+func (mm *MultiMap[K, V]) All() iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		for key, values := range mm.m.All() {
+			if !yield(key, *values) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package async
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestMultiMap(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMultiMap[string, int](4)
+	assert.IsNil(t, mm.Get("a"))
+	assert.Equal(t, mm.ContainsKey("a"), false)
+
+	mm.Add("a", 1)
+	mm.Add("a", 2)
+	mm.AddAll("a", 3, 2)
+	mm.AddAll("b")
+	mm.Add("b", 10)
+	assert.Equal(t, mm.Get("a"), []int{1, 2, 3, 2})
+	assert.Equal(t, mm.Count("a"), 4)
+	assert.Equal(t, mm.Count("b"), 1)
+	assert.Equal(t, mm.Count("c"), 0)
+	assert.Equal(t, mm.Size(), 2)
+	assert.Equal(t, mm.ContainsValue("a", 3), true)
+	assert.Equal(t, mm.ContainsValue("b", 3), false)
+
+	assert.Equal(t, mm.RemoveValue("a", 2), true)
+	assert.Equal(t, mm.Get("a"), []int{1, 3, 2})
+	assert.Equal(t, mm.RemoveValue("a", 5), false)
+	assert.Equal(t, mm.RemoveValue("c", 1), false)
+
+	assert.Equal(t, mm.RemoveValue("b", 10), true)
+	assert.Equal(t, mm.ContainsKey("b"), false)
+	assert.Equal(t, mm.Keys(), []string{"a"})
+	assert.Equal(t, maps.Collect(mm.All()), map[string][]int{"a": {1, 3, 2}})
+
+	assert.Equal(t, mm.RemoveAll("a"), []int{1, 3, 2})
+	assert.IsNil(t, mm.RemoveAll("a"))
+	assert.Equal(t, mm.Size(), 0)
+
+	mm.Add("c", 1)
+	mm.Clear()
+	assert.Equal(t, mm.Size(), 0)
+}
+
+func TestMultiMap_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMultiMap[int, int](2)
+	mm.AddAll(1, 1, 2, 3)
+	values := mm.Get(1)
+
+	// the snapshot is not affected by subsequent updates
+	mm.Add(1, 4)
+	assert.Equal(t, mm.RemoveValue(1, 1), true)
+	assert.Equal(t, values, []int{1, 2, 3})
+
+	// appending to a snapshot does not affect the map
+	_ = append(mm.Get(1), 5)
+	mm.Add(1, 6)
+	assert.Equal(t, mm.Get(1), []int{2, 3, 4, 6})
+}
+
+func TestMultiMap_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMultiMap[int, int](8)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				mm.Add(j%10, i*1000+j)
+				if j%2 == 0 {
+					assert.Equal(t, mm.RemoveValue(j%10, i*1000+j), true)
+				}
+				_ = mm.Get(j % 10)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int
+	for key, values := range mm.All() {
+		total += len(values)
+		for _, value := range values {
+			assert.Equal(t, value%10, key)
+			assert.Equal(t, value%2, 1)
+		}
+		sorted := slices.Sorted(slices.Values(values))
+		assert.Equal(t, len(slices.Compact(sorted)), len(values))
+	}
+	assert.Equal(t, total, 400)
+}
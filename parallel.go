@@ -0,0 +1,286 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ParallelOption configures the execution of the parallel helper functions
+// [ParallelMap], [ParallelForEach] and [ParallelReduce].
+type ParallelOption func(*parallelOptions)
+
+type parallelOptions struct {
+	stopOnError bool
+	// submit starts the worker, calling abandon if the worker is accepted
+	// but will never run
+	submit func(worker func(), abandon func(error)) error
+}
+
+// WithStopOnError configures a parallel helper to stop processing further
+// items once any item fails, and to return only the first error. By default,
+// all items are processed and all errors are returned joined.
+func WithStopOnError() ParallelOption {
+	return func(o *parallelOptions) {
+		o.stopOnError = true
+	}
+}
+
+// WithExecutor configures a parallel helper to run its workers on the given
+// [ExecutorService] instead of dedicated goroutines. Each worker occupies an
+// executor worker until all items are processed, so the executor queue must
+// be able to accept at least one worker. If the executor is shut down before
+// running a submitted worker, the processing is stopped and the executor error
+// is returned.
+func WithExecutor[T any](executor ExecutorService[T]) ParallelOption {
+	return func(o *parallelOptions) {
+		o.submit = func(worker func(), abandon func(error)) error {
+			var started atomic.Bool
+			future, err := executor.Submit(func(_ context.Context) (T, error) {
+				started.Store(true)
+				worker()
+				var zero T
+				return zero, nil
+			})
+			if err != nil {
+				return err
+			}
+			go func() {
+				// the job fails without running if the executor is shut down
+				if _, err := future.Join(); err != nil && !started.Load() {
+					abandon(err)
+				}
+			}()
+			return nil
+		}
+	}
+}
+
+// ParallelMap applies the function to every item of the slice using at most
+// n concurrent workers, and returns the results in the order of the items.
+// The results of failed or unprocessed items are zero values.
+// A panic in f is recovered, returned as an error and stops the processing.
+// If n is not positive, ParallelMap will panic.
+func ParallelMap[T, R any](ctx context.Context, items []T, n int,
+	f func(context.Context, T) (R, error), opts ...ParallelOption) ([]R, error) {
+	results := make([]R, len(items))
+	err := parallelRun(ctx, slices.Values(items), n, opts,
+		func(ctx context.Context, work <-chan parallelItem[T], fail func(error)) {
+			for item := range work {
+				result, err := f(ctx, item.value)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				results[item.index] = result
+			}
+		})
+	return results, err
+}
+
+// ParallelMapSeq applies the function to every item of the sequence using
+// at most n concurrent workers, and returns the results in the order of the
+// items. The results of failed or unprocessed items are zero values.
+// A panic in f is recovered, returned as an error and stops the processing.
+// If n is not positive, ParallelMapSeq will panic.
+func ParallelMapSeq[T, R any](ctx context.Context, items iter.Seq[T], n int,
+	f func(context.Context, T) (R, error), opts ...ParallelOption) ([]R, error) {
+	var mtx sync.Mutex
+	var results []R
+	var count int
+	counted := func(yield func(T) bool) {
+		for value := range items {
+			count++
+			if !yield(value) {
+				return
+			}
+		}
+	}
+	err := parallelRun(ctx, counted, n, opts,
+		func(ctx context.Context, work <-chan parallelItem[T], fail func(error)) {
+			for item := range work {
+				result, err := f(ctx, item.value)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mtx.Lock()
+				if item.index >= len(results) {
+					results = slices.Grow(results, item.index+1-len(results))[:item.index+1]
+				}
+				results[item.index] = result
+				mtx.Unlock()
+			}
+		})
+	if len(results) < count {
+		results = slices.Grow(results, count-len(results))[:count]
+	}
+	return results, err
+}
+
+// ParallelForEach calls the function for every item of the slice using at
+// most n concurrent workers.
+// A panic in f is recovered, returned as an error and stops the processing.
+// If n is not positive, ParallelForEach will panic.
+func ParallelForEach[T any](ctx context.Context, items []T, n int,
+	f func(context.Context, T) error, opts ...ParallelOption) error {
+	return ParallelForEachSeq(ctx, slices.Values(items), n, f, opts...)
+}
+
+// ParallelForEachSeq calls the function for every item of the sequence using
+// at most n concurrent workers.
+// A panic in f is recovered, returned as an error and stops the processing.
+// If n is not positive, ParallelForEachSeq will panic.
+func ParallelForEachSeq[T any](ctx context.Context, items iter.Seq[T], n int,
+	f func(context.Context, T) error, opts ...ParallelOption) error {
+	return parallelRun(ctx, items, n, opts,
+		func(ctx context.Context, work <-chan parallelItem[T], fail func(error)) {
+			for item := range work {
+				if err := f(ctx, item.value); err != nil {
+					fail(err)
+				}
+			}
+		})
+}
+
+// ParallelReduce reduces the items of the slice using at most n concurrent
+// workers. Each worker folds its share of the items into an accumulator
+// starting from initial using the reduce function, and the accumulators are
+// then merged using the combine function. Therefore, initial must be an
+// identity value for combine, which must be associative and commutative.
+// A panic in reduce or combine is recovered, returned as an error and stops
+// the processing.
+// If n is not positive, ParallelReduce will panic.
+func ParallelReduce[T, R any](ctx context.Context, items []T, n int, initial R,
+	reduce func(R, T) (R, error), combine func(R, R) R, opts ...ParallelOption) (R, error) {
+	return ParallelReduceSeq(ctx, slices.Values(items), n, initial, reduce, combine, opts...)
+}
+
+// ParallelReduceSeq reduces the items of the sequence using at most n
+// concurrent workers, as described in [ParallelReduce].
+// If n is not positive, ParallelReduceSeq will panic.
+func ParallelReduceSeq[T, R any](ctx context.Context, items iter.Seq[T], n int, initial R,
+	reduce func(R, T) (R, error), combine func(R, R) R, opts ...ParallelOption) (R, error) {
+	var mtx sync.Mutex
+	result := initial
+	err := parallelRun(ctx, items, n, opts,
+		func(_ context.Context, work <-chan parallelItem[T], fail func(error)) {
+			accumulator := initial
+			for item := range work {
+				next, err := reduce(accumulator, item.value)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				accumulator = next
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			result = combine(result, accumulator)
+		})
+	return result, err
+}
+
+type parallelItem[T any] struct {
+	index int
+	value T
+}
+
+// parallelRun distributes the items among n workers and waits for the
+// workers to return. The fail function records an item error, and cancels
+// the worker context if the stop on error option is set. A worker panic is
+// recovered and recorded as an error, which cancels the worker context.
+func parallelRun[T any](ctx context.Context, items iter.Seq[T], n int, opts []ParallelOption,
+	worker func(context.Context, <-chan parallelItem[T], func(error))) error {
+	if n < 1 {
+		panic(fmt.Sprintf("nonpositive number of workers: %d", n))
+	}
+	var options parallelOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var mtx sync.Mutex
+	var errs []error
+	fail := func(err error) {
+		mtx.Lock()
+		errs = append(errs, err)
+		mtx.Unlock()
+		if options.stopOnError {
+			cancel(err)
+		}
+	}
+
+	// start the workers
+	work := make(chan parallelItem[T])
+	var wg sync.WaitGroup
+	// stop records the error of a lost worker and stops distributing the items
+	stop := func(err error) {
+		mtx.Lock()
+		errs = append(errs, err)
+		mtx.Unlock()
+		cancel(err)
+	}
+	abandon := func(err error) {
+		// the worker will never run
+		defer wg.Done()
+		stop(err)
+	}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		run := func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					stop(fmt.Errorf("recovered: %v", r))
+				}
+			}()
+			worker(ctx, work, fail)
+		}
+		if options.submit == nil {
+			go run()
+		} else if err := options.submit(run, abandon); err != nil {
+			wg.Done()
+			if i == 0 {
+				// no worker could be started
+				return err
+			}
+			break
+		}
+	}
+
+	// distribute the items
+	index := 0
+	canceled := false
+	for value := range items {
+		select {
+		case work <- parallelItem[T]{index, value}:
+			index++
+		case <-ctx.Done():
+			canceled = true
+		}
+		if canceled {
+			break
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if canceled && len(errs) == 0 {
+		// the parent context is done
+		return context.Cause(ctx)
+	}
+	if options.stopOnError && len(errs) > 0 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,226 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestParallelMap(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	results, err := ParallelMap(context.Background(), items, 3,
+		func(_ context.Context, v int) (int, error) {
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Duration(8-v) * time.Millisecond)
+			running.Add(-1)
+			return v * 2, nil
+		})
+
+	assert.IsNil(t, err)
+	assert.Equal(t, []int{2, 4, 6, 8, 10, 12, 14, 16}, results)
+	if maxRunning.Load() > 3 {
+		t.Fatalf("max running workers is %d", maxRunning.Load())
+	}
+}
+
+func TestParallelMapSeq(t *testing.T) {
+	results, err := ParallelMapSeq(context.Background(), maps.Keys(map[int]struct{}{3: {}}), 2,
+		func(_ context.Context, v int) (int, error) {
+			return v * 2, nil
+		})
+	assert.IsNil(t, err)
+	assert.Equal(t, []int{6}, results)
+
+	strs, err := ParallelMapSeq(context.Background(), slices.Values([]string{"a", "b", "c"}), 2,
+		func(_ context.Context, v string) (string, error) {
+			return v + v, nil
+		})
+	assert.IsNil(t, err)
+	assert.Equal(t, []string{"aa", "bb", "cc"}, strs)
+
+	itemErr := errors.New("item error")
+	results, err = ParallelMapSeq(context.Background(), slices.Values([]int{1, 2, 3}), 2,
+		func(_ context.Context, v int) (int, error) {
+			if v == 3 {
+				return 0, itemErr
+			}
+			return v, nil
+		})
+	assert.ErrorIs(t, err, itemErr)
+	assert.Equal(t, []int{1, 2, 0}, results)
+}
+
+func TestParallelMap_Errors(t *testing.T) {
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+	results, err := ParallelMap(context.Background(), []int{1, 2, 3, 4}, 2,
+		func(_ context.Context, v int) (int, error) {
+			switch v {
+			case 1:
+				return 0, err1
+			case 3:
+				return 0, err2
+			}
+			return v, nil
+		})
+
+	assert.ErrorIs(t, err, err1)
+	assert.ErrorIs(t, err, err2)
+	assert.Equal(t, []int{0, 2, 0, 4}, results)
+}
+
+func TestParallelForEach_StopOnError(t *testing.T) {
+	itemErr := errors.New("item error")
+	var processed atomic.Int32
+	items := make([]int, 100)
+	err := ParallelForEach(context.Background(), items, 2,
+		func(ctx context.Context, _ int) error {
+			if processed.Add(1) == 3 {
+				return itemErr
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Millisecond):
+			}
+			return nil
+		}, WithStopOnError())
+
+	assert.Equal(t, itemErr, err)
+	if processed.Load() > 10 {
+		t.Fatalf("processed %d items", processed.Load())
+	}
+}
+
+func TestParallelForEach_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	infinite := func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+	err := ParallelForEachSeq(ctx, infinite, 2, func(_ context.Context, v int) error {
+		if v == 5 {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParallelReduce(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i + 1
+	}
+	sum, err := ParallelReduce(context.Background(), items, 4, 0,
+		func(acc int, v int) (int, error) {
+			return acc + v, nil
+		},
+		func(a, b int) int {
+			return a + b
+		})
+	assert.IsNil(t, err)
+	assert.Equal(t, 5050, sum)
+}
+
+func TestParallel_Executor(t *testing.T) {
+	executor := NewExecutor[string](context.Background(), NewExecutorConfig(2, 2))
+	defer executor.Shutdown()
+
+	results, err := ParallelMap(context.Background(), []int{1, 2, 3, 4, 5}, 4,
+		func(_ context.Context, v int) (int, error) {
+			return v * v, nil
+		}, WithExecutor(executor))
+	assert.IsNil(t, err)
+	assert.Equal(t, []int{1, 4, 9, 16, 25}, results)
+
+	_ = executor.Shutdown()
+	time.Sleep(5 * time.Millisecond)
+	err = ParallelForEach(context.Background(), []int{1}, 1,
+		func(_ context.Context, _ int) error {
+			return nil
+		}, WithExecutor(executor))
+	assert.ErrorIs(t, err, ErrExecutorShutDown)
+}
+
+func TestParallel_ExecutorShutdown(t *testing.T) {
+	executor := NewExecutor[string](context.Background(), NewExecutorConfig(1, 4))
+	// occupy the only executor worker until the shutdown
+	_, err := executor.Submit(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	assert.IsNil(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ParallelForEach(context.Background(), []int{1, 2, 3, 4, 5}, 2,
+			func(_ context.Context, _ int) error {
+				return nil
+			}, WithExecutor(executor))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_ = executor.Shutdown()
+
+	select {
+	case err = <-done:
+		// the executor may still run a queued worker while shutting down
+		if err != nil {
+			assert.ErrorIs(t, err, ErrExecutorShutDown)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ParallelForEach did not return after the executor shutdown")
+	}
+}
+
+func TestParallel_Validation(t *testing.T) {
+	assert.PanicMsgContains(t, func() {
+		_ = ParallelForEach(context.Background(), []int{1}, 0,
+			func(_ context.Context, _ int) error { return nil })
+	}, "nonpositive number of workers")
+}
+
+func TestParallel_Panic(t *testing.T) {
+	executor := NewExecutor[string](context.Background(), NewExecutorConfig(2, 2))
+	defer executor.Shutdown()
+
+	for _, opts := range [][]ParallelOption{nil, {WithExecutor(executor)}} {
+		done := make(chan error, 1)
+		go func() {
+			done <- ParallelForEach(context.Background(), []int{1, 2, 3, 4, 5}, 1,
+				func(_ context.Context, v int) error {
+					if v == 1 {
+						panic("item panic")
+					}
+					return nil
+				}, opts...)
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorContains(t, err, "recovered: item panic")
+		case <-time.After(time.Second):
+			t.Fatal("ParallelForEach did not return after a worker panic")
+		}
+	}
+
+	_, err := ParallelReduce(context.Background(), []int{1, 2, 3, 4}, 2, 0,
+		func(acc int, v int) (int, error) {
+			return acc + v, nil
+		},
+		func(_, _ int) int {
+			panic("combine panic")
+		}, WithExecutor(executor))
+	assert.ErrorContains(t, err, "recovered: combine panic")
+}
@@ -0,0 +1,260 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// errPipelineClosed is the cancellation cause used when the consumer stops
+// reading the pipeline output. It is not reported as a pipeline error.
+var errPipelineClosed = errors.New("async: pipeline is closed")
+
+// StageConfig represents the configuration of a pipeline stage.
+type StageConfig struct {
+	// Name is the stage name reported in the stage statistics.
+	Name string
+	// Parallelism is the number of workers processing stage items.
+	// A non-positive value means a single worker.
+	Parallelism int
+	// BufferSize is the number of processed items which can be buffered
+	// before the stage blocks. It must be non-negative.
+	BufferSize int
+	// Ordered specifies whether the stage emits results in the order
+	// of its input items.
+	Ordered bool
+}
+
+// StageStats represents a point-in-time snapshot of pipeline stage
+// statistics.
+type StageStats struct {
+	Name      string
+	Processed int64 // number of successfully processed items
+	Failed    int64 // number of items for which processing failed
+	InFlight  int64 // number of items currently being processed or buffered
+}
+
+// Pipeline is a multi-stage concurrent processing pipeline. Each stage runs
+// its function on an [Executor] with the configured parallelism, and passes
+// results to the next stage through a bounded buffer. The first error
+// returned by a stage function cancels all stages of the pipeline.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	mtx    sync.Mutex
+	stages []*stageStats
+	err    error
+}
+
+// Stage represents the output of a pipeline stage, which can be used as
+// the input of the next stage or consumed using [Stage.All].
+type Stage[T any] struct {
+	pipeline *Pipeline
+	ch       chan T
+}
+
+type stageStats struct {
+	name      string
+	processed atomic.Int64
+	failed    atomic.Int64
+	inFlight  atomic.Int64
+}
+
+// NewPipeline returns a new Pipeline derived from ctx.
+func NewPipeline(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Pipeline{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// PipelineSource returns a new pipeline Stage emitting the values of the
+// given sequence.
+func PipelineSource[T any](p *Pipeline, seq iter.Seq[T]) *Stage[T] {
+	out := &Stage[T]{
+		pipeline: p,
+		ch:       make(chan T),
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out.ch)
+		for value := range seq {
+			if !pipelineEmit(p.ctx, out.ch, value) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// PipelineStage returns a new pipeline Stage applying the function to
+// every value emitted by the input Stage. The function receives a context,
+// which is canceled when the pipeline fails or is closed.
+func PipelineStage[In, Out any](in *Stage[In], config StageConfig,
+	f func(context.Context, In) (Out, error)) *Stage[Out] {
+	p := in.pipeline
+	parallelism := max(config.Parallelism, 1)
+	capacity := parallelism + config.BufferSize
+	out := &Stage[Out]{
+		pipeline: p,
+		ch:       make(chan Out, config.BufferSize),
+	}
+	stats := p.addStage(config.Name)
+	executor := NewExecutor[Out](p.ctx, NewExecutorConfig(parallelism, capacity))
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out.ch)
+		defer func() { _ = executor.Shutdown() }()
+
+		// the semaphore limits the number of in-flight items, so that
+		// submissions never exceed the executor queue capacity
+		sem := make(chan struct{}, capacity)
+		complete := func(result Out, err error) {
+			if err != nil {
+				// do not count items discarded due to cancellation
+				if p.ctx.Err() == nil {
+					stats.failed.Add(1)
+				}
+				p.fail(err)
+			} else {
+				stats.processed.Add(1)
+				pipelineEmit(p.ctx, out.ch, result)
+			}
+			stats.inFlight.Add(-1)
+			<-sem
+		}
+
+		var wg sync.WaitGroup
+		var pending chan Future[Out]
+		if config.Ordered {
+			// complete the results in the order of submission
+			pending = make(chan Future[Out], capacity)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for future := range pending {
+					complete(future.Join())
+				}
+			}()
+		}
+
+	loop:
+		for value := range in.ch {
+			select {
+			case sem <- struct{}{}:
+			case <-p.ctx.Done():
+				break loop
+			}
+			stats.inFlight.Add(1)
+			future, err := executor.Submit(func(ctx context.Context) (Out, error) {
+				return f(ctx, value)
+			})
+			if err != nil {
+				future = FailedFuture[Out](err)
+			}
+			if config.Ordered {
+				pending <- future
+			} else {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					complete(future.Join())
+				}()
+			}
+		}
+
+		if pending != nil {
+			close(pending)
+		}
+		wg.Wait()
+	}()
+	return out
+}
+
+// All returns an iterator over the values emitted by the Stage. The output
+// of the last pipeline stage must be consumed for the pipeline to complete.
+// Breaking out of the iteration cancels the pipeline.
+func (s *Stage[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for value := range s.ch {
+			if !yield(value) {
+				s.pipeline.cancel(errPipelineClosed)
+				return
+			}
+		}
+	}
+}
+
+// Wait blocks until all stages of the pipeline have completed and returns
+// the first error returned by a stage function, or the context error if the
+// pipeline context was canceled.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	defer p.cancel(nil)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if cause := context.Cause(p.ctx); cause != nil && !errors.Is(cause, errPipelineClosed) {
+		return cause
+	}
+	return nil
+}
+
+// Stats returns the statistics of the pipeline stages, in the order in
+// which the stages were added.
+func (p *Pipeline) Stats() []StageStats {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	stats := make([]StageStats, 0, len(p.stages))
+	for _, stage := range p.stages {
+		stats = append(stats, StageStats{
+			Name:      stage.name,
+			Processed: stage.processed.Load(),
+			Failed:    stage.failed.Load(),
+			InFlight:  stage.inFlight.Load(),
+		})
+	}
+	return stats
+}
+
+// addStage registers statistics for a new stage.
+func (p *Pipeline) addStage(name string) *stageStats {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	stats := &stageStats{name: name}
+	p.stages = append(p.stages, stats)
+	return stats
+}
+
+// fail records the first error and cancels the pipeline.
+func (p *Pipeline) fail(err error) {
+	p.mtx.Lock()
+	if p.err == nil && p.ctx.Err() == nil {
+		p.err = err
+	}
+	p.mtx.Unlock()
+	p.cancel(err)
+}
+
+// pipelineEmit sends the value to the channel, unless the pipeline context
+// is done. It returns false if the value was not sent.
+func pipelineEmit[T any](ctx context.Context, ch chan<- T, value T) bool {
+	select {
+	case ch <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,115 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestPipeline_Ordered(t *testing.T) {
+	p := NewPipeline(context.Background())
+	source := PipelineSource(p, slices.Values([]int{1, 2, 3, 4, 5, 6, 7, 8}))
+	parsed := PipelineStage(source, StageConfig{Name: "parse", Parallelism: 4, BufferSize: 2, Ordered: true},
+		func(_ context.Context, v int) (int, error) {
+			time.Sleep(time.Duration(8-v) * time.Millisecond)
+			return v * 10, nil
+		})
+	formatted := PipelineStage(parsed, StageConfig{Name: "format", Parallelism: 2, Ordered: true},
+		func(_ context.Context, v int) (string, error) {
+			return strconv.Itoa(v), nil
+		})
+
+	results := slices.Collect(formatted.All())
+	assert.IsNil(t, p.Wait())
+	assert.Equal(t, []string{"10", "20", "30", "40", "50", "60", "70", "80"}, results)
+
+	stats := p.Stats()
+	assert.Equal(t, []StageStats{
+		{Name: "parse", Processed: 8},
+		{Name: "format", Processed: 8},
+	}, stats)
+}
+
+func TestPipeline_Unordered(t *testing.T) {
+	p := NewPipeline(context.Background())
+	source := PipelineSource(p, slices.Values([]int{1, 2, 3, 4, 5, 6}))
+	squared := PipelineStage(source, StageConfig{Name: "square", Parallelism: 3},
+		func(_ context.Context, v int) (int, error) {
+			return v * v, nil
+		})
+
+	results := slices.Collect(squared.All())
+	assert.IsNil(t, p.Wait())
+	assert.ElementsMatch(t, []int{1, 4, 9, 16, 25, 36}, results)
+}
+
+func TestPipeline_Error(t *testing.T) {
+	stageErr := errors.New("stage error")
+	p := NewPipeline(context.Background())
+	source := PipelineSource(p, func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+	failing := PipelineStage(source, StageConfig{Name: "fail", Parallelism: 2},
+		func(_ context.Context, v int) (int, error) {
+			if v == 10 {
+				return 0, stageErr
+			}
+			return v, nil
+		})
+	sink := PipelineStage(failing, StageConfig{Name: "sink", Parallelism: 1, Ordered: true},
+		func(_ context.Context, v int) (int, error) {
+			return v, nil
+		})
+
+	for range sink.All() {
+	}
+	assert.ErrorIs(t, p.Wait(), stageErr)
+	assert.Equal(t, int64(1), p.Stats()[0].Failed)
+}
+
+func TestPipeline_Close(t *testing.T) {
+	p := NewPipeline(context.Background())
+	source := PipelineSource(p, func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+	stage := PipelineStage(source, StageConfig{Name: "identity", Parallelism: 2, BufferSize: 4},
+		func(_ context.Context, v int) (int, error) {
+			return v, nil
+		})
+
+	for v := range stage.All() {
+		if v > 5 {
+			break
+		}
+	}
+	assert.IsNil(t, p.Wait())
+}
+
+func TestPipeline_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPipeline(ctx)
+	source := PipelineSource(p, slices.Values([]int{1, 2, 3}))
+	stage := PipelineStage(source, StageConfig{Name: "wait", Parallelism: 1},
+		func(ctx context.Context, _ int) (int, error) {
+			cancel()
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+
+	for range stage.All() {
+	}
+	assert.ErrorIs(t, p.Wait(), context.Canceled)
+}
@@ -1,6 +1,6 @@
 package async
 
-import "sync"
+import "sync/atomic"
 
 // Promise represents a writable, single-assignment container,
 // which completes a Future.
@@ -12,14 +12,37 @@ type Promise[T any] interface {
 	// Failure fails the underlying Future with an error.
 	Failure(error)
 
+	// TrySuccess attempts to complete the underlying Future with a value.
+	// It returns true if this call completed the Future, or false if the
+	// Future was already completed.
+	TrySuccess(T) bool
+
+	// TryFailure attempts to fail the underlying Future with an error.
+	// It returns true if this call completed the Future, or false if the
+	// Future was already completed.
+	TryFailure(error) bool
+
+	// TryComplete attempts to complete the underlying Future with either
+	// a value or an error. It returns true if this call completed the
+	// Future, or false if the Future was already completed.
+	TryComplete(T, error) bool
+
+	// CompleteWith completes the underlying Future with the result of
+	// another Future, once it is available.
+	CompleteWith(Future[T])
+
+	// IsCompleted returns true if the underlying Future has already
+	// been completed.
+	IsCompleted() bool
+
 	// Future returns the underlying Future.
 	Future() Future[T]
 }
 
 // promiseImpl implements the Promise interface.
 type promiseImpl[T any] struct {
-	once   sync.Once
-	future Future[T]
+	completed atomic.Bool
+	future    *futureImpl[T]
 }
 
 // Verify promiseImpl satisfies the Promise interface.
@@ -33,18 +56,59 @@ func NewPromise[T any]() Promise[T] {
 }
 
 // Success completes the underlying Future with a given value.
+// Subsequent completion attempts are ignored.
 func (p *promiseImpl[T]) Success(value T) {
-	p.once.Do(func() {
-		p.future.complete(value, nil)
-	})
+	p.TrySuccess(value)
 }
 
 // Failure fails the underlying Future with a given error.
+// Subsequent completion attempts are ignored.
 func (p *promiseImpl[T]) Failure(err error) {
-	p.once.Do(func() {
+	p.TryFailure(err)
+}
+
+// TrySuccess attempts to complete the underlying Future with a given value.
+// It returns true if this call completed the Future.
+func (p *promiseImpl[T]) TrySuccess(value T) bool {
+	return p.TryComplete(value, nil)
+}
+
+// TryFailure attempts to fail the underlying Future with a given error.
+// It returns true if this call completed the Future.
+func (p *promiseImpl[T]) TryFailure(err error) bool {
+	var zero T
+	return p.TryComplete(zero, err)
+}
+
+// TryComplete attempts to complete the underlying Future with either a value
+// or an error. If err is not nil, the Future is failed and the value is
+// discarded. It returns true if this call completed the Future.
+func (p *promiseImpl[T]) TryComplete(value T, err error) bool {
+	if !p.completed.CompareAndSwap(false, true) {
+		return false
+	}
+	if err != nil {
 		var zero T
 		p.future.complete(zero, err)
-	})
+	} else {
+		p.future.complete(value, nil)
+	}
+	return true
+}
+
+// CompleteWith completes the underlying Future with the result of the given
+// Future, once it is available. If the underlying Future is completed by
+// other means first, the result of the given Future is discarded.
+func (p *promiseImpl[T]) CompleteWith(future Future[T]) {
+	go func() {
+		p.TryComplete(future.Join())
+	}()
+}
+
+// IsCompleted returns true if the underlying Future has already been
+// completed.
+func (p *promiseImpl[T]) IsCompleted() bool {
+	return p.completed.Load()
 }
 
 // Future returns the underlying Future.
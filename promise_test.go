@@ -0,0 +1,77 @@
+package async
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestPromise_TryComplete(t *testing.T) {
+	p := NewPromise[int]()
+	assert.Equal(t, false, p.IsCompleted())
+
+	assert.Equal(t, true, p.TrySuccess(1))
+	assert.Equal(t, true, p.IsCompleted())
+	assert.Equal(t, false, p.TrySuccess(2))
+	assert.Equal(t, false, p.TryFailure(errors.New("error")))
+	assert.Equal(t, false, p.TryComplete(3, nil))
+	p.Success(4)
+
+	result, err := p.Future().Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 1, result)
+}
+
+func TestPromise_TryCompleteFailure(t *testing.T) {
+	p := NewPromise[int]()
+	err := errors.New("error")
+	assert.Equal(t, true, p.TryComplete(1, err))
+	assert.Equal(t, false, p.TrySuccess(2))
+
+	result, resultErr := p.Future().Join()
+	assert.ErrorIs(t, resultErr, err)
+	assert.Equal(t, 0, result)
+}
+
+func TestPromise_TryCompleteRace(t *testing.T) {
+	p := NewPromise[int]()
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p.TrySuccess(i) {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), wins.Load())
+	assert.Equal(t, true, p.IsCompleted())
+}
+
+func TestPromise_CompleteWith(t *testing.T) {
+	source := NewPromise[string]()
+	p := NewPromise[string]()
+	p.CompleteWith(source.Future())
+	source.Success("ok")
+
+	result, err := p.Future().Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, true, p.IsCompleted())
+
+	failed := NewPromise[string]()
+	err = errors.New("error")
+	source = NewPromise[string]()
+	source.Failure(err)
+	failed.CompleteWith(source.Future())
+
+	_, resultErr := failed.Future().Join()
+	assert.ErrorIs(t, resultErr, err)
+}
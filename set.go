@@ -0,0 +1,222 @@
+package async
+
+import "iter"
+
+// A Set is a collection that contains no duplicate elements.
+//
+// The set algebra operations (Difference, Intersection, SymmetricDifference
+// and Union) return a new set of the same implementation as the receiver.
+// They copy the elements of the operands before testing the membership, so
+// that no lock is held across the operands, and the result is weakly
+// consistent with concurrent updates of the operands.
+type Set[K comparable] interface {
+
+	// Add adds the specified element to this set.
+	Add(element K)
+
+	// AddIfAbsent adds the specified element to this set if it is not
+	// already present. It returns true if the element was added.
+	AddIfAbsent(element K) bool
+
+	// Clear removes all of the elements from this set.
+	Clear()
+
+	// Contains returns true if this set contains the specified element.
+	Contains(element K) bool
+
+	// Difference returns a new set of the elements of this set that are not
+	// contained in the other set.
+	Difference(other Set[K]) Set[K]
+
+	// Intersection returns a new set of the elements contained in both this
+	// set and the other set.
+	Intersection(other Set[K]) Set[K]
+
+	// IsEmpty returns true if this set contains no elements.
+	IsEmpty() bool
+
+	// Remove removes the specified element from this set if it is present.
+	// It returns true if the element was removed.
+	Remove(element K) bool
+
+	// Size returns the number of elements in this set.
+	Size() int
+
+	// SymmetricDifference returns a new set of the elements contained in
+	// either this set or the other set, but not in both.
+	SymmetricDifference(other Set[K]) Set[K]
+
+	// Union returns a new set of the elements contained in either this set
+	// or the other set.
+	Union(other Set[K]) Set[K]
+
+	// Values returns a slice of the elements contained in this set.
+	Values() []K
+
+	// All returns an iterator of all elements in this set.
+	// The order of the elements is not specified.
+	All() iter.Seq[K]
+}
+
+// setPresent is the value mapped to each element of a mapSet.
+var setPresent = &struct{}{}
+
+// mapSet implements the Set interface on top of a Map.
+type mapSet[K comparable] struct {
+	m      Map[K, struct{}]
+	newSet func() Set[K]
+}
+
+// Add adds the specified element to this set.
+func (s *mapSet[K]) Add(element K) {
+	s.m.Put(element, setPresent)
+}
+
+// AddIfAbsent adds the specified element to this set if it is not
+// already present. It returns true if the element was added.
+func (s *mapSet[K]) AddIfAbsent(element K) bool {
+	return s.m.PutIfAbsent(element, setPresent) == nil
+}
+
+// Clear removes all of the elements from this set.
+func (s *mapSet[K]) Clear() {
+	s.m.Clear()
+}
+
+// Contains returns true if this set contains the specified element.
+func (s *mapSet[K]) Contains(element K) bool {
+	return s.m.ContainsKey(element)
+}
+
+// Difference returns a new set of the elements of this set that are not
+// contained in the other set.
+func (s *mapSet[K]) Difference(other Set[K]) Set[K] {
+	result := s.newSet()
+	for _, element := range s.Values() {
+		if !other.Contains(element) {
+			result.Add(element)
+		}
+	}
+	return result
+}
+
+// Intersection returns a new set of the elements contained in both this
+// set and the other set.
+func (s *mapSet[K]) Intersection(other Set[K]) Set[K] {
+	result := s.newSet()
+	for _, element := range s.Values() {
+		if other.Contains(element) {
+			result.Add(element)
+		}
+	}
+	return result
+}
+
+// IsEmpty returns true if this set contains no elements.
+func (s *mapSet[K]) IsEmpty() bool {
+	return s.m.IsEmpty()
+}
+
+// Remove removes the specified element from this set if it is present.
+// It returns true if the element was removed.
+func (s *mapSet[K]) Remove(element K) bool {
+	return s.m.Remove(element) != nil
+}
+
+// Size returns the number of elements in this set.
+func (s *mapSet[K]) Size() int {
+	return s.m.Size()
+}
+
+// SymmetricDifference returns a new set of the elements contained in
+// either this set or the other set, but not in both.
+func (s *mapSet[K]) SymmetricDifference(other Set[K]) Set[K] {
+	result := s.Difference(other)
+	for _, element := range other.Values() {
+		if !s.Contains(element) {
+			result.Add(element)
+		}
+	}
+	return result
+}
+
+// Union returns a new set of the elements contained in either this set
+// or the other set.
+func (s *mapSet[K]) Union(other Set[K]) Set[K] {
+	result := s.newSet()
+	for _, element := range s.Values() {
+		result.Add(element)
+	}
+	for _, element := range other.Values() {
+		result.Add(element)
+	}
+	return result
+}
+
+// Values returns a slice of the elements contained in this set.
+func (s *mapSet[K]) Values() []K {
+	return s.m.KeySet()
+}
+
+// All returns an iterator of all elements in this set.
+// The order of the elements is not specified.
+func (s *mapSet[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for element := range s.m.All() {
+			if !yield(element) {
+				return
+			}
+		}
+	}
+}
+
+// ConcurrentSet implements the async.Set interface in a thread-safe manner,
+// storing the elements in an async.ConcurrentMap.
+type ConcurrentSet[K comparable] struct {
+	mapSet[K]
+}
+
+var _ Set[int] = (*ConcurrentSet[int])(nil)
+
+// NewConcurrentSet returns a new ConcurrentSet instance.
+func NewConcurrentSet[K comparable]() *ConcurrentSet[K] {
+	set := &ConcurrentSet[K]{}
+	set.m = NewConcurrentMap[K, struct{}]()
+	set.newSet = func() Set[K] { return NewConcurrentSet[K]() }
+	return set
+}
+
+// ShardedSet implements the async.Set interface in a thread-safe manner,
+// storing the elements in an async.ShardedMap.
+type ShardedSet[K comparable] struct {
+	mapSet[K]
+}
+
+var _ Set[int] = (*ShardedSet[int])(nil)
+
+// NewShardedSet returns a new ShardedSet, where shards is the number of
+// partitions for this set. The sets produced by the set algebra operations
+// have the same number of shards.
+// If the shards argument is not positive, NewShardedSet will panic.
+func NewShardedSet[K comparable](shards int) *ShardedSet[K] {
+	set := &ShardedSet[K]{}
+	set.m = NewShardedMap[K, struct{}](shards)
+	set.newSet = func() Set[K] { return NewShardedSet[K](shards) }
+	return set
+}
+
+// SynchronizedSet implements the async.Set interface in a thread-safe manner,
+// storing the elements in an async.SynchronizedMap.
+type SynchronizedSet[K comparable] struct {
+	mapSet[K]
+}
+
+var _ Set[int] = (*SynchronizedSet[int])(nil)
+
+// NewSynchronizedSet returns a new SynchronizedSet instance.
+func NewSynchronizedSet[K comparable]() *SynchronizedSet[K] {
+	set := &SynchronizedSet[K]{}
+	set.m = NewSynchronizedMap[K, struct{}]()
+	set.newSet = func() Set[K] { return NewSynchronizedSet[K]() }
+	return set
+}
@@ -0,0 +1,177 @@
+package async
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestSet_AddRemove(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestSets() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := tt.newSet()
+			assert.Equal(t, s.IsEmpty(), true)
+
+			s.Add(1)
+			s.Add(1)
+			assert.Equal(t, s.AddIfAbsent(2), true)
+			assert.Equal(t, s.AddIfAbsent(2), false)
+			assert.Equal(t, s.Size(), 2)
+			assert.Equal(t, s.Contains(1), true)
+			assert.Equal(t, s.Contains(3), false)
+
+			assert.Equal(t, s.Remove(1), true)
+			assert.Equal(t, s.Remove(1), false)
+			assert.Equal(t, s.Values(), []int{2})
+			assert.Equal(t, slices.Collect(s.All()), []int{2})
+
+			s.Clear()
+			assert.Equal(t, s.IsEmpty(), true)
+		})
+	}
+}
+
+func TestSet_Algebra(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestSets() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := tt.newSet()
+			for _, element := range []int{1, 2, 3, 4} {
+				a.Add(element)
+			}
+			// the other operand may be of a different implementation
+			b := NewSynchronizedSet[int]()
+			for _, element := range []int{3, 4, 5} {
+				b.Add(element)
+			}
+
+			assertSet(t, a.Union(b), tt.newSet(), []int{1, 2, 3, 4, 5})
+			assertSet(t, a.Intersection(b), tt.newSet(), []int{3, 4})
+			assertSet(t, a.Difference(b), tt.newSet(), []int{1, 2})
+			assertSet(t, a.SymmetricDifference(b), tt.newSet(), []int{1, 2, 5})
+
+			// the operands are not modified
+			assertSet(t, a, tt.newSet(), []int{1, 2, 3, 4})
+			assertSet(t, b, NewSynchronizedSet[int](), []int{3, 4, 5})
+		})
+	}
+}
+
+func TestSet_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestSets() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := tt.newSet()
+
+			var wg sync.WaitGroup
+			var added sync.Map
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 500; j++ {
+						if s.AddIfAbsent(j) {
+							if _, dup := added.LoadOrStore(j, i); dup {
+								t.Errorf("element %d added twice", j)
+							}
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, s.Size(), 500)
+		})
+	}
+}
+
+func TestSet_AlgebraConcurrent(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range prepareTestSets() {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a, b := tt.newSet(), tt.newSet()
+			for i := 0; i < 100; i++ {
+				a.Add(i)
+				b.Add(i + 50)
+			}
+
+			var wg sync.WaitGroup
+			stop := make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					a.Add(i % 200)
+					b.Remove(i % 200)
+				}
+			}()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					_ = a.Intersection(a)
+					_ = a.SymmetricDifference(b)
+					_ = b.SymmetricDifference(a)
+					_ = a.Union(b)
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("set algebra deadlocked")
+			}
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
+
+func assertSet(t *testing.T, s, expectedType Set[int], expected []int) {
+	t.Helper()
+	assert.Equal(t, typeName(s), typeName(expectedType))
+	values := s.Values()
+	slices.Sort(values)
+	assert.Equal(t, values, expected)
+}
+
+func typeName(s Set[int]) string {
+	switch s.(type) {
+	case *ConcurrentSet[int]:
+		return "ConcurrentSet"
+	case *ShardedSet[int]:
+		return "ShardedSet"
+	case *SynchronizedSet[int]:
+		return "SynchronizedSet"
+	default:
+		return "unknown"
+	}
+}
+
+func prepareTestSets() []testSet {
+	return []testSet{
+		{"concurrentSet", func() Set[int] { return NewConcurrentSet[int]() }},
+		{"shardedSet", func() Set[int] { return NewShardedSet[int](4) }},
+		{"synchronizedSet", func() Set[int] { return NewSynchronizedSet[int]() }},
+	}
+}
+
+type testSet struct {
+	name   string
+	newSet func() Set[int]
+}
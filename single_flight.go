@@ -0,0 +1,123 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SingleFlight provides a duplicate call suppression mechanism. Concurrent
+// calls for the same key share a single in-flight execution of the function,
+// and receive its result through their own Futures.
+//
+// The shared function runs with a context which is detached from the
+// cancellation of the individual callers. A canceled caller is released
+// with the context error without affecting the others, and the shared
+// function context is canceled only when all of its callers are canceled.
+type SingleFlight[K comparable, V any] struct {
+	mtx   sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// flightCall represents an in-flight or completed function call.
+type flightCall[V any] struct {
+	cancel   context.CancelFunc
+	waiters  []*flightWaiter[V]
+	active   int // number of callers still waiting for the result
+	finished bool
+}
+
+type flightWaiter[V any] struct {
+	promise Promise[V]
+	stop    func() bool
+}
+
+// NewSingleFlight returns a new SingleFlight.
+func NewSingleFlight[K comparable, V any]() *SingleFlight[K, V] {
+	return &SingleFlight[K, V]{
+		calls: make(map[K]*flightCall[V]),
+	}
+}
+
+// Do executes the function for the key in a new goroutine, unless a call
+// for the key is already in flight, in which case the caller joins it.
+// It returns a Future which will be completed with the result of the shared
+// call, or with the context error if ctx is done first.
+func (sf *SingleFlight[K, V]) Do(ctx context.Context, key K,
+	f func(context.Context) (V, error)) Future[V] {
+	waiter := &flightWaiter[V]{promise: NewPromise[V]()}
+
+	sf.mtx.Lock()
+	call, ok := sf.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall[V]{cancel: cancel}
+		sf.calls[key] = call
+		go sf.run(callCtx, key, call, f)
+	}
+	call.waiters = append(call.waiters, waiter)
+	call.active++
+	waiter.stop = context.AfterFunc(ctx, func() {
+		if waiter.promise.TryFailure(ctx.Err()) {
+			sf.leave(key, call)
+		}
+	})
+	sf.mtx.Unlock()
+
+	return waiter.promise.Future()
+}
+
+// Forget tells the SingleFlight to forget about the key. Subsequent calls
+// of Do for the key will execute the function rather than joining an
+// in-flight call. Callers already waiting for the result are not affected.
+func (sf *SingleFlight[K, V]) Forget(key K) {
+	sf.mtx.Lock()
+	defer sf.mtx.Unlock()
+	delete(sf.calls, key)
+}
+
+// run executes the function and completes the Futures of all waiters.
+func (sf *SingleFlight[K, V]) run(ctx context.Context, key K, call *flightCall[V],
+	f func(context.Context) (V, error)) {
+	value, err := sf.call(ctx, f)
+
+	sf.mtx.Lock()
+	if sf.calls[key] == call {
+		delete(sf.calls, key)
+	}
+	call.finished = true
+	waiters := call.waiters
+	sf.mtx.Unlock()
+
+	call.cancel()
+	for _, waiter := range waiters {
+		waiter.stop()
+		waiter.promise.TryComplete(value, err)
+	}
+}
+
+// call executes the function, handling possible panics.
+func (sf *SingleFlight[K, V]) call(ctx context.Context,
+	f func(context.Context) (V, error)) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+	return f(ctx)
+}
+
+// leave removes a canceled caller from the call. If no callers are left,
+// the call is canceled and forgotten.
+func (sf *SingleFlight[K, V]) leave(key K, call *flightCall[V]) {
+	sf.mtx.Lock()
+	defer sf.mtx.Unlock()
+
+	call.active--
+	if call.active == 0 && !call.finished {
+		call.cancel()
+		if sf.calls[key] == call {
+			delete(sf.calls, key)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestSingleFlight(t *testing.T) {
+	sf := NewSingleFlight[string, int]()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	f := func(_ context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 1, nil
+	}
+
+	futures := make([]Future[int], 10)
+	for i := range futures {
+		futures[i] = sf.Do(context.Background(), "key", f)
+	}
+	other := sf.Do(context.Background(), "other", f)
+	close(release)
+
+	assertFutureResult(t, 1, futures...)
+	assertFutureResult(t, 1, other)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// the completed call is not shared with subsequent callers
+	assertFutureResult(t, 1, sf.Do(context.Background(), "key", f))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestSingleFlight_Concurrent(t *testing.T) {
+	sf := NewSingleFlight[int, int]()
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	futures := make([]Future[int], 100)
+	for i := range futures {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			futures[i] = sf.Do(context.Background(), 1, func(_ context.Context) (int, error) {
+				calls.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return 2, nil
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assertFutureResult(t, 2, futures...)
+
+	if calls.Load() > 2 {
+		t.Fatalf("function called %d times", calls.Load())
+	}
+}
+
+func TestSingleFlight_Forget(t *testing.T) {
+	sf := NewSingleFlight[string, int]()
+	release := make(chan struct{})
+	first := sf.Do(context.Background(), "key", func(_ context.Context) (int, error) {
+		<-release
+		return 1, nil
+	})
+	sf.Forget("key")
+	second := sf.Do(context.Background(), "key", func(_ context.Context) (int, error) {
+		return 2, nil
+	})
+
+	assertFutureResult(t, 2, second)
+	close(release)
+	assertFutureResult(t, 1, first)
+}
+
+func TestSingleFlight_CallerCanceled(t *testing.T) {
+	sf := NewSingleFlight[string, int]()
+	release := make(chan struct{})
+	var callErr atomic.Value
+	f := func(ctx context.Context) (int, error) {
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			callErr.Store(ctx.Err())
+			return 0, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := sf.Do(ctx, "key", f)
+	waiting := sf.Do(context.Background(), "key", f)
+	cancel()
+
+	_, err := canceled.Join()
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	assertFutureResult(t, 1, waiting)
+	assert.IsNil(t, callErr.Load())
+}
+
+func TestSingleFlight_AllCallersCanceled(t *testing.T) {
+	sf := NewSingleFlight[string, int]()
+	stopped := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	future := sf.Do(ctx, "key", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return 0, ctx.Err()
+	})
+	cancel()
+
+	_, err := future.Join()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+
+	// the canceled call is forgotten
+	assertFutureResult(t, 1, sf.Do(context.Background(), "key",
+		func(_ context.Context) (int, error) {
+			return 1, nil
+		}))
+}
+
+func TestSingleFlight_Error(t *testing.T) {
+	sf := NewSingleFlight[string, int]()
+	callErr := errors.New("call error")
+	_, err := sf.Do(context.Background(), "key", func(_ context.Context) (int, error) {
+		return 0, callErr
+	}).Join()
+	assert.ErrorIs(t, err, callErr)
+
+	_, err = sf.Do(context.Background(), "key", func(_ context.Context) (int, error) {
+		panic("call panic")
+	}).Join()
+	assert.ErrorContains(t, err, "call panic")
+}
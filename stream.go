@@ -0,0 +1,213 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// errStreamClosed is the cancellation cause used when the consumer closes
+// the stream. It is not reported as a stream error.
+var errStreamClosed = errors.New("async: stream is closed")
+
+// Stream represents an asynchronous sequence of values, which are produced
+// by a goroutine and consumed by a single consumer. The producer is blocked
+// while the stream buffer is full, which provides backpressure.
+// A Stream can be consumed only once.
+type Stream[T any] struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	ch     chan T
+	done   chan struct{}
+	err    error
+}
+
+// NewStream returns a new Stream, whose values are produced by the produce
+// function running in a new goroutine. The produce function sends values
+// using the emit function, which blocks while the buffer of the given size
+// is full, and returns an error if the stream is canceled or closed, in which
+// case the produce function should return that error. The error returned by
+// the produce function is reported by [Stream.Err].
+func NewStream[T any](ctx context.Context, bufferSize int,
+	produce func(ctx context.Context, emit func(T) error) error) *Stream[T] {
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	stream := &Stream[T]{
+		parent: ctx,
+		ctx:    streamCtx,
+		cancel: cancel,
+		ch:     make(chan T, bufferSize),
+		done:   make(chan struct{}),
+	}
+	go stream.run(produce)
+	return stream
+}
+
+// StreamFromSeq returns a new unbuffered Stream producing the values of the
+// given sequence.
+func StreamFromSeq[T any](ctx context.Context, seq iter.Seq[T]) *Stream[T] {
+	return NewStream(ctx, 0, func(_ context.Context, emit func(T) error) error {
+		for value := range seq {
+			if err := emit(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// StreamMap returns a new Stream by applying a function to every value of
+// the given Stream. If the function returns an error, the new Stream fails
+// with that error and the source Stream is closed.
+func StreamMap[T, R any](stream *Stream[T], f func(T) (R, error)) *Stream[R] {
+	return pipeStream(stream, 0, func(value T, emit func(R) error) error {
+		result, err := f(value)
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	})
+}
+
+// StreamBatch returns a new Stream grouping the values of the given Stream
+// into slices of the given size. The last batch may contain fewer values.
+// If the size is not positive, StreamBatch will panic.
+func StreamBatch[T any](stream *Stream[T], size int) *Stream[[]T] {
+	if size < 1 {
+		panic(fmt.Sprintf("nonpositive batch size: %d", size))
+	}
+	return NewStream(stream.parent, 0, func(ctx context.Context, emit func([]T) error) error {
+		stop := context.AfterFunc(ctx, stream.Close)
+		defer stop()
+		defer stream.Close()
+
+		batch := make([]T, 0, size)
+		for value := range stream.ch {
+			batch = append(batch, value)
+			if len(batch) == size {
+				if err := emit(batch); err != nil {
+					return err
+				}
+				batch = make([]T, 0, size)
+			}
+		}
+		if err := stream.Err(); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			return emit(batch)
+		}
+		return nil
+	})
+}
+
+// Filter returns a new Stream containing only the values of this Stream
+// satisfying the predicate.
+func (s *Stream[T]) Filter(predicate func(T) bool) *Stream[T] {
+	return pipeStream(s, 0, func(value T, emit func(T) error) error {
+		if predicate(value) {
+			return emit(value)
+		}
+		return nil
+	})
+}
+
+// Buffer returns a new Stream containing the values of this Stream, which
+// allows the producer to run ahead of the consumer by up to size values.
+func (s *Stream[T]) Buffer(size int) *Stream[T] {
+	return pipeStream(s, size, func(value T, emit func(T) error) error {
+		return emit(value)
+	})
+}
+
+// All returns an iterator over the values of the Stream. The iteration ends
+// when the producer returns; use [Stream.Err] to check whether it failed.
+// Breaking out of the iteration closes the Stream.
+func (s *Stream[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for value := range s.ch {
+			if !yield(value) {
+				s.Close()
+				return
+			}
+		}
+	}
+}
+
+// Collect returns a Future which will be completed with a slice of all
+// values of the Stream, or with the error of the Stream producer.
+func (s *Stream[T]) Collect() Future[[]T] {
+	promise := NewPromise[[]T]()
+	go func() {
+		var values []T
+		for value := range s.All() {
+			values = append(values, value)
+		}
+		if err := s.Err(); err != nil {
+			promise.Failure(err)
+		} else {
+			promise.Success(values)
+		}
+	}()
+	return promise.Future()
+}
+
+// Close closes the Stream, signaling the producer to stop.
+// Values that have not been consumed yet are discarded.
+func (s *Stream[T]) Close() {
+	s.cancel(errStreamClosed)
+}
+
+// Err blocks until the producer of the Stream returns and reports its error.
+// It returns nil if the producer completed successfully or the Stream was
+// closed by the consumer.
+func (s *Stream[T]) Err() error {
+	<-s.done
+	return s.err
+}
+
+// run executes the produce function, handling possible panics.
+func (s *Stream[T]) run(produce func(context.Context, func(T) error) error) {
+	defer close(s.done)
+	defer func() {
+		if r := recover(); r != nil {
+			s.err = fmt.Errorf("recovered: %v", r)
+		}
+		close(s.ch)
+		s.cancel(nil)
+	}()
+	if err := produce(s.ctx, s.emit); !errors.Is(err, errStreamClosed) {
+		s.err = err
+	}
+}
+
+// emit sends the value to the consumer, blocking while the buffer is full.
+func (s *Stream[T]) emit(value T) error {
+	select {
+	case s.ch <- value:
+		return nil
+	case <-s.ctx.Done():
+		return context.Cause(s.ctx)
+	}
+}
+
+// pipeStream returns a new Stream, whose values are produced by applying the
+// process function to the values of the source Stream. The source Stream is
+// closed when the new Stream is done.
+func pipeStream[T, R any](stream *Stream[T], bufferSize int,
+	process func(T, func(R) error) error) *Stream[R] {
+	return NewStream(stream.parent, bufferSize, func(ctx context.Context, emit func(R) error) error {
+		// close the source if the new stream is canceled while waiting for a value
+		stop := context.AfterFunc(ctx, stream.Close)
+		defer stop()
+		defer stream.Close()
+
+		for value := range stream.ch {
+			if err := process(value, emit); err != nil {
+				return err
+			}
+		}
+		return stream.Err()
+	})
+}
@@ -0,0 +1,143 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestStream(t *testing.T) {
+	stream := NewStream(context.Background(), 2, func(_ context.Context, emit func(int) error) error {
+		for i := 0; i < 5; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var values []int
+	for value := range stream.All() {
+		values = append(values, value)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, values)
+	assert.IsNil(t, stream.Err())
+}
+
+func TestStream_Operators(t *testing.T) {
+	stream := StreamFromSeq(context.Background(), slices.Values([]int{1, 2, 3, 4, 5, 6, 7}))
+	mapped := StreamMap(stream.Filter(func(v int) bool {
+		return v%2 == 1
+	}).Buffer(2), func(v int) (string, error) {
+		return strconv.Itoa(v * 10), nil
+	})
+
+	result, err := StreamBatch(mapped, 3).Collect().Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, [][]string{{"10", "30", "50"}, {"70"}}, result)
+}
+
+func TestStream_Error(t *testing.T) {
+	streamErr := errors.New("stream error")
+	stream := StreamFromSeq(context.Background(), slices.Values([]int{1, 2, 3}))
+	mapped := StreamMap(stream, func(v int) (int, error) {
+		if v == 2 {
+			return 0, streamErr
+		}
+		return v, nil
+	})
+
+	_, err := mapped.Collect().Join()
+	assert.ErrorIs(t, err, streamErr)
+	assert.IsNil(t, stream.Err())
+
+	failed := NewStream(context.Background(), 0, func(_ context.Context, emit func(int) error) error {
+		_ = emit(1)
+		return streamErr
+	})
+	_, err = StreamBatch(failed.Filter(func(int) bool { return true }), 2).Collect().Join()
+	assert.ErrorIs(t, err, streamErr)
+}
+
+func TestStream_Panic(t *testing.T) {
+	stream := NewStream(context.Background(), 0, func(_ context.Context, _ func(int) error) error {
+		panic("producer panic")
+	})
+	_, err := stream.Collect().Join()
+	assert.ErrorContains(t, err, "producer panic")
+}
+
+func TestStream_Break(t *testing.T) {
+	var produced atomic.Int32
+	source := NewStream(context.Background(), 0, func(_ context.Context, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+			produced.Add(1)
+		}
+	})
+	stream := source.Buffer(1)
+
+	for value := range stream.All() {
+		if value == 2 {
+			break
+		}
+	}
+	assert.IsNil(t, stream.Err())
+	assert.IsNil(t, source.Err())
+	if produced.Load() > 5 {
+		t.Fatalf("produced %d values", produced.Load())
+	}
+}
+
+func TestStream_Backpressure(t *testing.T) {
+	var produced atomic.Int32
+	stream := NewStream(context.Background(), 2, func(_ context.Context, emit func(int) error) error {
+		for i := 0; i < 10; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+			produced.Add(1)
+		}
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(2), produced.Load())
+	stream.Close()
+	assert.IsNil(t, stream.Err())
+}
+
+func TestStream_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := NewStream(ctx, 0, func(_ context.Context, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+	})
+	mapped := StreamMap(stream, func(v int) (int, error) {
+		return v, nil
+	})
+	for value := range mapped.All() {
+		if value == 3 {
+			cancel()
+		}
+	}
+	assert.ErrorIs(t, mapped.Err(), context.Canceled)
+	assert.ErrorIs(t, stream.Err(), context.Canceled)
+}
+
+func TestStream_BatchSize(t *testing.T) {
+	stream := StreamFromSeq(context.Background(), slices.Values([]int{1}))
+	assert.PanicMsgContains(t, func() { StreamBatch(stream, 0) }, "nonpositive batch size")
+	stream.Close()
+}
@@ -1,13 +1,26 @@
 package async
 
+import "context"
+
 // Task is a data type for controlling possibly lazy and
 // asynchronous computations.
 type Task[T any] struct {
-	taskFunc func() (T, error)
+	taskFunc func(context.Context) (T, error)
 }
 
 // NewTask returns a new Task associated with the specified function.
 func NewTask[T any](taskFunc func() (T, error)) *Task[T] {
+	return &Task[T]{
+		taskFunc: func(_ context.Context) (T, error) {
+			return taskFunc()
+		},
+	}
+}
+
+// NewTaskContext returns a new Task associated with the specified
+// context-aware function. The function has the same signature as the
+// functions submitted to an [ExecutorService].
+func NewTaskContext[T any](taskFunc func(context.Context) (T, error)) *Task[T] {
 	return &Task[T]{
 		taskFunc: taskFunc,
 	}
@@ -17,10 +30,26 @@ func NewTask[T any](taskFunc func() (T, error)) *Task[T] {
 // Future which can be used to retrieve the result or error of the
 // task when it is completed.
 func (task *Task[T]) Call() Future[T] {
+	return task.CallContext(context.Background())
+}
+
+// CallContext starts executing the task using a goroutine, passing ctx
+// to the task function. It returns a Future which can be used to retrieve
+// the result or error of the task when it is completed.
+// If ctx is done before the task function returns, the Future is completed
+// with the context error.
+func (task *Task[T]) CallContext(ctx context.Context) Future[T] {
 	promise := NewPromise[T]()
+	stop := context.AfterFunc(ctx, func() {
+		promise.Failure(ctx.Err())
+	})
 	go func() {
-		result, err := task.taskFunc()
-		if err == nil {
+		defer stop()
+		result, err := task.taskFunc(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// the task may observe the cancellation before the callback runs
+			promise.Failure(ctxErr)
+		} else if err == nil {
 			promise.Success(result)
 		} else {
 			promise.Failure(err)
@@ -0,0 +1,219 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTaskGraphCycle       = errors.New("async: task graph contains a cycle")
+	ErrTaskDependencyFailed = errors.New("async: task dependency failed")
+)
+
+// TaskGraph is a directed acyclic graph of tasks, where each task may depend
+// on the results of other tasks. Independent tasks are executed concurrently
+// on an [ExecutorService] once all of their dependencies have completed.
+// A failed task causes all of its dependents to fail without being executed.
+type TaskGraph[T any] struct {
+	mtx   sync.Mutex
+	nodes map[string]*taskGraphNode[T]
+	ids   []string // in insertion order
+	order []string // in topological order, set by Build
+}
+
+type taskGraphNode[T any] struct {
+	id       string
+	deps     []string
+	taskFunc func(context.Context, map[string]T) (T, error)
+}
+
+// NewTaskGraph returns a new empty TaskGraph.
+func NewTaskGraph[T any]() *TaskGraph[T] {
+	return &TaskGraph[T]{
+		nodes: make(map[string]*taskGraphNode[T]),
+	}
+}
+
+// Add adds a task with the given id to the graph. The task depends on the
+// tasks identified by dependsOn, which do not need to be added yet. The task
+// function receives the results of its dependencies keyed by their ids.
+// Add returns an error if the id is already in use or the graph is built.
+func (g *TaskGraph[T]) Add(id string, taskFunc func(context.Context, map[string]T) (T, error),
+	dependsOn ...string) error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.order != nil {
+		return fmt.Errorf("async: task graph is already built, cannot add %q", id)
+	}
+	if _, ok := g.nodes[id]; ok {
+		return fmt.Errorf("async: duplicate task %q", id)
+	}
+	g.nodes[id] = &taskGraphNode[T]{
+		id:       id,
+		deps:     append([]string(nil), dependsOn...),
+		taskFunc: taskFunc,
+	}
+	g.ids = append(g.ids, id)
+	return nil
+}
+
+// Build validates the graph and freezes its structure. It returns an error
+// if a task depends on an unknown task, or ErrTaskGraphCycle if the
+// dependencies contain a cycle. Once built, no more tasks can be added.
+func (g *TaskGraph[T]) Build() error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.build()
+}
+
+func (g *TaskGraph[T]) build() error {
+	if g.order != nil {
+		return nil
+	}
+
+	// count the number of unresolved dependencies for each task
+	pending := make(map[string]int, len(g.nodes))
+	dependents := make(map[string][]string, len(g.nodes))
+	for _, id := range g.ids {
+		node := g.nodes[id]
+		for _, dep := range node.deps {
+			if _, ok := g.nodes[dep]; !ok {
+				return fmt.Errorf("async: task %q depends on unknown task %q", id, dep)
+			}
+			dependents[dep] = append(dependents[dep], id)
+		}
+		pending[id] = len(node.deps)
+	}
+
+	// sort the tasks topologically using Kahn's algorithm
+	order := make([]string, 0, len(g.ids))
+	for _, id := range g.ids {
+		if pending[id] == 0 {
+			order = append(order, id)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		for _, dependent := range dependents[order[i]] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				order = append(order, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(g.ids) {
+		cyclic := make([]string, 0, len(g.ids)-len(order))
+		for _, id := range g.ids {
+			if pending[id] > 0 {
+				cyclic = append(cyclic, id)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrTaskGraphCycle, strings.Join(cyclic, ", "))
+	}
+	g.order = order
+	return nil
+}
+
+// Run builds the graph if required and schedules all of its tasks for
+// execution on the executor. It returns a map of Futures keyed by task id,
+// each of which will be completed with the result or error of the task.
+// A task whose dependency fails is completed with an error wrapping
+// ErrTaskDependencyFailed. If ctx is done, tasks that have not yet been
+// submitted fail with the context error, and running tasks observe the
+// cancellation through their context. Ready tasks are retried while the
+// executor queue is full.
+func (g *TaskGraph[T]) Run(ctx context.Context, executor ExecutorService[T]) (map[string]Future[T], error) {
+	g.mtx.Lock()
+	err := g.build()
+	g.mtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	futures := make(map[string]Future[T], len(g.order))
+	promises := make(map[string]Promise[T], len(g.order))
+	for _, id := range g.order {
+		promise := NewPromise[T]()
+		promises[id] = promise
+		futures[id] = promise.Future()
+	}
+	for _, id := range g.order {
+		go g.runNode(ctx, executor, g.nodes[id], promises[id], futures)
+	}
+	return futures, nil
+}
+
+// runNode waits for the dependencies of the node to complete and submits
+// the node task to the executor.
+func (g *TaskGraph[T]) runNode(ctx context.Context, executor ExecutorService[T],
+	node *taskGraphNode[T], promise Promise[T], futures map[string]Future[T]) {
+	results := make(map[string]T, len(node.deps))
+	for _, dep := range node.deps {
+		result, err := futures[dep].Join()
+		if err != nil {
+			promise.Failure(fmt.Errorf("%w: %s: %w", ErrTaskDependencyFailed, dep, err))
+			return
+		}
+		results[dep] = result
+	}
+	if err := ctx.Err(); err != nil {
+		promise.Failure(err)
+		return
+	}
+
+	future, err := submitWhenQueued(ctx, executor, func(execCtx context.Context) (T, error) {
+		// propagate cancellation of the run context to the task
+		execCtx, cancel := context.WithCancelCause(execCtx)
+		defer cancel(nil)
+		stop := context.AfterFunc(ctx, func() {
+			cancel(context.Cause(ctx))
+		})
+		defer stop()
+		return node.taskFunc(execCtx, results)
+	})
+	if err != nil {
+		promise.Failure(err)
+		return
+	}
+
+	result, err := future.Join()
+	if err != nil {
+		promise.Failure(err)
+	} else {
+		promise.Success(result)
+	}
+}
+
+// Bounds of the delay between the submission attempts to a full executor queue.
+const (
+	minSubmitRetryDelay = time.Millisecond
+	maxSubmitRetryDelay = 50 * time.Millisecond
+)
+
+// submitWhenQueued submits the task to the executor, retrying with an
+// exponential backoff while the executor queue is full, so that a graph
+// may have more ready tasks than the executor can accept at once.
+// It returns the context error if ctx is done before the task is queued.
+func submitWhenQueued[T any](ctx context.Context, executor ExecutorService[T],
+	task func(context.Context) (T, error)) (Future[T], error) {
+	delay := minSubmitRetryDelay
+	for {
+		future, err := executor.Submit(task)
+		if !errors.Is(err, ErrExecutorQueueFull) {
+			return future, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, context.Cause(ctx)
+		case <-timer.C:
+		}
+		delay = min(2*delay, maxSubmitRetryDelay)
+	}
+}
@@ -0,0 +1,168 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestTaskGraph(t *testing.T) {
+	ctx := context.Background()
+	executor := NewExecutor[int](ctx, NewExecutorConfig(4, 8))
+	defer executor.Shutdown()
+
+	var mtx sync.Mutex
+	var order []string
+	record := func(id string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		order = append(order, id)
+	}
+
+	graph := NewTaskGraph[int]()
+	assert.IsNil(t, graph.Add("sum", func(_ context.Context, deps map[string]int) (int, error) {
+		record("sum")
+		return deps["a"] + deps["b"], nil
+	}, "a", "b"))
+	assert.IsNil(t, graph.Add("a", func(_ context.Context, _ map[string]int) (int, error) {
+		time.Sleep(time.Millisecond)
+		record("a")
+		return 1, nil
+	}))
+	assert.IsNil(t, graph.Add("b", func(_ context.Context, deps map[string]int) (int, error) {
+		record("b")
+		return deps["a"] + 1, nil
+	}, "a"))
+	assert.IsNil(t, graph.Build())
+
+	futures, err := graph.Run(ctx, executor)
+	assert.IsNil(t, err)
+
+	result, err := futures["sum"].Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 3, result)
+	assert.Equal(t, []string{"a", "b", "sum"}, order)
+}
+
+func TestTaskGraph_QueueFull(t *testing.T) {
+	ctx := context.Background()
+	executor := NewExecutor[int](ctx, NewExecutorConfig(2, 2))
+	defer executor.Shutdown()
+
+	// more ready tasks than the executor workers and queue slots
+	graph := NewTaskGraph[int]()
+	deps := make([]string, 10)
+	for i := range deps {
+		deps[i] = strconv.Itoa(i)
+		assert.IsNil(t, graph.Add(deps[i], func(_ context.Context, _ map[string]int) (int, error) {
+			time.Sleep(time.Millisecond)
+			return i, nil
+		}))
+	}
+	assert.IsNil(t, graph.Add("sum", func(_ context.Context, results map[string]int) (int, error) {
+		var sum int
+		for _, result := range results {
+			sum += result
+		}
+		return sum, nil
+	}, deps...))
+
+	futures, err := graph.Run(ctx, executor)
+	assert.IsNil(t, err)
+
+	result, err := futures["sum"].Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 45, result)
+}
+
+func TestTaskGraph_Failure(t *testing.T) {
+	ctx := context.Background()
+	executor := NewExecutor[int](ctx, NewExecutorConfig(2, 4))
+	defer executor.Shutdown()
+
+	taskErr := errors.New("task error")
+	graph := NewTaskGraph[int]()
+	_ = graph.Add("a", func(_ context.Context, _ map[string]int) (int, error) {
+		return 0, taskErr
+	})
+	_ = graph.Add("b", func(_ context.Context, _ map[string]int) (int, error) {
+		t.Error("dependent task executed")
+		return 1, nil
+	}, "a")
+	_ = graph.Add("c", func(_ context.Context, _ map[string]int) (int, error) {
+		return 2, nil
+	})
+
+	futures, err := graph.Run(ctx, executor)
+	assert.IsNil(t, err)
+
+	_, err = futures["a"].Join()
+	assert.ErrorIs(t, err, taskErr)
+	_, err = futures["b"].Join()
+	assert.ErrorIs(t, err, ErrTaskDependencyFailed)
+	assert.ErrorIs(t, err, taskErr)
+	result, err := futures["c"].Join()
+	assert.IsNil(t, err)
+	assert.Equal(t, 2, result)
+}
+
+func TestTaskGraph_Cycle(t *testing.T) {
+	task := func(_ context.Context, _ map[string]int) (int, error) {
+		return 0, nil
+	}
+	graph := NewTaskGraph[int]()
+	_ = graph.Add("a", task, "c")
+	_ = graph.Add("b", task, "a")
+	_ = graph.Add("c", task, "b")
+	_ = graph.Add("d", task)
+
+	err := graph.Build()
+	assert.ErrorIs(t, err, ErrTaskGraphCycle)
+	assert.ErrorContains(t, err, "a, b, c")
+
+	_, err = graph.Run(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrTaskGraphCycle)
+}
+
+func TestTaskGraph_Validation(t *testing.T) {
+	task := func(_ context.Context, _ map[string]int) (int, error) {
+		return 0, nil
+	}
+	graph := NewTaskGraph[int]()
+	assert.IsNil(t, graph.Add("a", task, "b"))
+	assert.ErrorContains(t, graph.Add("a", task), "duplicate task")
+	assert.ErrorContains(t, graph.Build(), "unknown task")
+
+	assert.IsNil(t, graph.Add("b", task))
+	assert.IsNil(t, graph.Build())
+	assert.ErrorContains(t, graph.Add("c", task), "already built")
+}
+
+func TestTaskGraph_Canceled(t *testing.T) {
+	executor := NewExecutor[int](context.Background(), NewExecutorConfig(2, 4))
+	defer executor.Shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	graph := NewTaskGraph[int]()
+	_ = graph.Add("a", func(ctx context.Context, _ map[string]int) (int, error) {
+		cancel()
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	_ = graph.Add("b", func(_ context.Context, _ map[string]int) (int, error) {
+		return 1, nil
+	}, "a")
+
+	futures, err := graph.Run(ctx, executor)
+	assert.IsNil(t, err)
+
+	_, err = futures["a"].Join()
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = futures["b"].Join()
+	assert.ErrorIs(t, err, ErrTaskDependencyFailed)
+}
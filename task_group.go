@@ -0,0 +1,109 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TaskGroup is a collection of tasks working on subtasks of the same overall
+// computation under a common parent context. The first task to fail cancels
+// the context shared by all tasks in the group, and Wait blocks until every
+// task has returned, so that no goroutine started by the group outlives it.
+//
+// A TaskGroup must not be reused after Wait has returned.
+type TaskGroup[T any] struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     *WaitGroupContext
+	sem    chan struct{}
+	mtx    sync.Mutex
+	errs   []error
+}
+
+// NewTaskGroup returns a new TaskGroup derived from ctx. The limit argument
+// bounds the number of tasks running concurrently; a non-positive limit
+// means no limit.
+func NewTaskGroup[T any](ctx context.Context, limit int) *TaskGroup[T] {
+	ctx, cancel := context.WithCancelCause(ctx)
+	group := &TaskGroup[T]{
+		ctx:    ctx,
+		cancel: cancel,
+		// the group must wait for all tasks regardless of the context state
+		wg: NewWaitGroupContext(context.Background()),
+	}
+	if limit > 0 {
+		group.sem = make(chan struct{}, limit)
+	}
+	return group
+}
+
+// Go starts executing the task in a new goroutine, passing the group context
+// to the task function. It returns a Future which will be completed with the
+// result or error of the task.
+// If the concurrency limit is reached, Go blocks until a running task returns
+// or the group context is done. In the latter case, the task is not started
+// and the returned Future is failed with the context cancellation cause.
+func (g *TaskGroup[T]) Go(task *Task[T]) Future[T] {
+	promise := NewPromise[T]()
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			promise.Failure(context.Cause(g.ctx))
+			return promise.Future()
+		}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.release()
+		result, err := g.run(task)
+		if err != nil {
+			g.fail(err)
+			promise.Failure(err)
+		} else {
+			promise.Success(result)
+		}
+	}()
+	return promise.Future()
+}
+
+// Wait blocks until all tasks started by the group have returned, then
+// cancels the group context. It returns all errors returned by the tasks,
+// joined using errors.Join, or nil if every task succeeded.
+func (g *TaskGroup[T]) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return errors.Join(g.errs...)
+}
+
+// run executes the task function, handling possible panics.
+func (g *TaskGroup[T]) run(task *Task[T]) (result T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered: %v", r)
+		}
+	}()
+	return task.taskFunc(g.ctx)
+}
+
+// fail records the task error and cancels the group context.
+func (g *TaskGroup[T]) fail(err error) {
+	g.mtx.Lock()
+	g.errs = append(g.errs, err)
+	g.mtx.Unlock()
+	g.cancel(err)
+}
+
+// release frees the task slot and marks the task as done.
+func (g *TaskGroup[T]) release() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
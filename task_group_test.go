@@ -0,0 +1,123 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reugn/async/internal/assert"
+)
+
+func TestTaskGroup_Success(t *testing.T) {
+	group := NewTaskGroup[int](context.Background(), 0)
+	futures := make([]Future[int], 0, 5)
+	for i := 0; i < 5; i++ {
+		futures = append(futures, group.Go(NewTask(func() (int, error) {
+			time.Sleep(time.Millisecond)
+			return i, nil
+		})))
+	}
+
+	assert.IsNil(t, group.Wait())
+	for i, future := range futures {
+		result, err := future.Join()
+		assert.IsNil(t, err)
+		assert.Equal(t, i, result)
+	}
+}
+
+func TestTaskGroup_CancelOnFailure(t *testing.T) {
+	taskErr := errors.New("task error")
+	var canceled atomic.Int32
+	group := NewTaskGroup[int](context.Background(), 0)
+	for i := 0; i < 3; i++ {
+		group.Go(NewTaskContext(func(ctx context.Context) (int, error) {
+			select {
+			case <-ctx.Done():
+				canceled.Add(1)
+				return 0, nil
+			case <-time.After(time.Second):
+				return 1, nil
+			}
+		}))
+	}
+	failed := group.Go(NewTask(func() (int, error) {
+		return 0, taskErr
+	}))
+
+	err := group.Wait()
+	assert.ErrorIs(t, err, taskErr)
+	assert.Equal(t, int32(3), canceled.Load())
+
+	_, err = failed.Join()
+	assert.ErrorIs(t, err, taskErr)
+}
+
+func TestTaskGroup_JoinErrors(t *testing.T) {
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+	group := NewTaskGroup[int](context.Background(), 0)
+	start := make(chan struct{})
+	for _, err := range []error{err1, err2} {
+		group.Go(NewTask(func() (int, error) {
+			<-start
+			return 0, err
+		}))
+	}
+	close(start)
+
+	err := group.Wait()
+	assert.ErrorIs(t, err, err1)
+	assert.ErrorIs(t, err, err2)
+}
+
+func TestTaskGroup_Limit(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	group := NewTaskGroup[int](context.Background(), 2)
+	for i := 0; i < 10; i++ {
+		group.Go(NewTask(func() (int, error) {
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return i, nil
+		}))
+	}
+
+	assert.IsNil(t, group.Wait())
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestTaskGroup_LimitCanceled(t *testing.T) {
+	taskErr := errors.New("task error")
+	group := NewTaskGroup[int](context.Background(), 1)
+	group.Go(NewTask(func() (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		return 0, taskErr
+	}))
+	future := group.Go(NewTask(func() (int, error) {
+		return 1, nil
+	}))
+
+	_, err := future.Join()
+	assert.ErrorIs(t, err, taskErr)
+	assert.ErrorIs(t, group.Wait(), taskErr)
+}
+
+func TestTaskGroup_Panic(t *testing.T) {
+	group := NewTaskGroup[int](context.Background(), 0)
+	future := group.Go(NewTask(func() (int, error) {
+		panic("task panic")
+	}))
+
+	assert.ErrorContains(t, group.Wait(), "task panic")
+	_, err := future.Join()
+	assert.ErrorContains(t, err, "recovered")
+}
@@ -1,6 +1,7 @@
 package async
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -41,3 +42,66 @@ func TestTask_Failure(t *testing.T) {
 	assert.IsNil(t, res)
 	assert.ErrorContains(t, err, "error")
 }
+
+func TestTask_Context(t *testing.T) {
+	task := NewTaskContext(func(ctx context.Context) (string, error) {
+		if ctx.Value(taskCtxKey{}) == nil {
+			return "", errors.New("context value not found")
+		}
+		return "ok", nil
+	})
+	ctx := context.WithValue(context.Background(), taskCtxKey{}, true)
+	res, err := task.CallContext(ctx).Join()
+
+	assert.Equal(t, "ok", res)
+	assert.IsNil(t, err)
+}
+
+func TestTask_ContextCanceled(t *testing.T) {
+	observed := make(chan error, 1)
+	task := NewTaskContext(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		observed <- ctx.Err()
+		return "ok", nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res, err := task.CallContext(ctx).Join()
+
+	assert.Equal(t, "", res)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, <-observed, context.DeadlineExceeded)
+}
+
+func TestTask_ContextCanceledReturnImmediately(t *testing.T) {
+	// the task returns as soon as it observes the cancellation, racing
+	// with the context callback
+	for i := 0; i < 100; i++ {
+		task := NewTaskContext(func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "ok", nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		future := task.CallContext(ctx)
+		cancel()
+		res, err := future.Join()
+
+		assert.Equal(t, "", res)
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestTask_ContextAlreadyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	task := NewTask(func() (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	})
+	res, err := task.CallContext(ctx).Join()
+
+	assert.Equal(t, 0, res)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+type taskCtxKey struct{}